@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"golang.design/x/clipboard"
+	"os"
+	"runtime"
+	"time"
+)
+
+// copySecret writes secret to the clipboard and clears it again after
+// timeout, unless the clipboard has been overwritten in the meantime.
+// It blocks until the clipboard was cleared or changed, as some platforms
+// only keep clipboard content while the writing process is alive. On those
+// a timeout of zero blocks until the clipboard changes.
+func copySecret(name string, secret []byte, timeout time.Duration) error {
+	err := clipboard.Init()
+	if err != nil {
+		return fmt.Errorf("failed to access clipboard: %w", err)
+	}
+	changed := clipboard.Write(clipboard.FmtText, secret)
+	if timeout <= 0 {
+		if clipboardOutlivesProcess() {
+			return nil
+		}
+		fmt.Fprintf(os.Stderr, "Copied %s to clipboard. Keeping it until the clipboard changes.\n", name)
+		<-changed
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "Copied %s to clipboard. Will clear in %s.\n", name, timeout)
+	select {
+	case <-changed:
+		logger.Debug("clipboard changed, not clearing it")
+	case <-time.After(timeout):
+		if bytes.Equal(clipboard.Read(clipboard.FmtText), secret) {
+			clipboard.Write(clipboard.FmtText, []byte{})
+		}
+	}
+	return nil
+}
+
+// clipboardOutlivesProcess reports whether the clipboard of the platform
+// keeps content after the process that wrote it exited. X11 and Wayland
+// clipboards are served by the writing process.
+func clipboardOutlivesProcess() bool {
+	return runtime.GOOS == "darwin" || runtime.GOOS == "windows"
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"io"
+	"os"
+	"strings"
+	"tasadar.net/tionis/shell-tools/codec"
+)
+
+func encodeCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "encode",
+		Usage:     "encode stdin or a file with a binary-to-text codec",
+		ArgsUsage: "[file]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "codec",
+				Aliases: []string{"c"},
+				Usage:   "codec to use: " + strings.Join(codec.Names, ", "),
+				Value:   codec.Base64,
+			},
+			&cli.IntFlag{
+				Name:    "wrap",
+				Aliases: []string{"w"},
+				Usage:   "wrap encoded lines after this many characters, 0 disables wrapping",
+				Value:   76,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			in, err := codecInput(c)
+			if err != nil {
+				return err
+			}
+			defer in.Close()
+			encoder, err := codec.NewEncoder(c.String("codec"), os.Stdout, c.Int("wrap"))
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(encoder, in)
+			if err == nil {
+				err = encoder.Close()
+			}
+			if err != nil {
+				return fmt.Errorf("failed to encode: %w", err)
+			}
+			return nil
+		},
+	}
+}
+
+func decodeCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "decode",
+		Usage:     "decode stdin or a file encoded with a binary-to-text codec",
+		ArgsUsage: "[file]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "codec",
+				Aliases: []string{"c"},
+				Usage:   "codec to use, detected if not given: " + strings.Join(codec.Names, ", "),
+			},
+			&cli.BoolFlag{
+				Name:    "ignore-garbage",
+				Aliases: []string{"i"},
+				Usage:   "ignore characters not used by the codec",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			in, err := codecInput(c)
+			if err != nil {
+				return err
+			}
+			defer in.Close()
+			var decoder io.Reader
+			if c.String("codec") == "" {
+				var name string
+				decoder, name, err = codec.NewDetectingDecoder(in, c.Bool("ignore-garbage"))
+				logger.Debug("detected codec", "codec", name)
+			} else {
+				decoder, err = codec.NewDecoder(c.String("codec"), in, c.Bool("ignore-garbage"))
+			}
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(os.Stdout, decoder)
+			if err != nil {
+				return fmt.Errorf("failed to decode: %w", err)
+			}
+			return nil
+		},
+	}
+}
+
+// codecInput opens the file given as argument, or stdin.
+func codecInput(c *cli.Context) (io.ReadCloser, error) {
+	switch c.Args().Len() {
+	case 0:
+		return io.NopCloser(os.Stdin), nil
+	case 1:
+		if c.Args().First() == "-" {
+			return io.NopCloser(os.Stdin), nil
+		}
+		file, err := os.Open(c.Args().First())
+		if err != nil {
+			return nil, fmt.Errorf("failed to open input: %w", err)
+		}
+		return file, nil
+	default:
+		return nil, errors.New("expected at most one file")
+	}
+}
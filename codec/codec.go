@@ -0,0 +1,262 @@
+// Package codec implements streaming binary-to-text encodings: base64,
+// base32, hex, ascii85, percent-encoding and quoted-printable.
+package codec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/ascii85"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime/quotedprintable"
+	"strings"
+)
+
+// Names of the codecs.
+const (
+	Base64       = "base64"
+	Base64Raw    = "base64raw"
+	Base64URL    = "base64url"
+	Base64URLRaw = "base64urlraw"
+	Base32       = "base32"
+	Hex          = "hex"
+	ASCII85      = "ascii85"
+	URL          = "url"
+	QP           = "qp"
+)
+
+// Names lists all codecs.
+var Names = []string{Base64, Base64Raw, Base64URL, Base64URLRaw, Base32, Hex, ASCII85, URL, QP} //nolint: gochecknoglobals
+
+const (
+	base64Alphabet    = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+	base64URLAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+	base32Alphabet    = "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567="
+	hexAlphabet       = "0123456789abcdefABCDEF"
+)
+
+// ascii85Alphabet holds the characters of ascii85 data, including the z
+// abbreviation for four zero bytes.
+var ascii85Alphabet = func() string { //nolint: gochecknoglobals
+	var b strings.Builder
+	for c := '!'; c <= 'u'; c++ {
+		b.WriteRune(c)
+	}
+	return b.String() + "z"
+}()
+
+// Check returns an error if name is not a known codec.
+func Check(name string) error {
+	for _, known := range Names {
+		if name == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown codec %q, expected one of %s", name, strings.Join(Names, ", "))
+}
+
+// NewEncoder returns a writer encoding what is written to it with the codec
+// name to w. If wrap is positive, encoded output is broken into lines of
+// wrap characters, ending with a newline. Quoted-printable output is always
+// wrapped at 76 characters, ascii85 output is delimited by <~ and ~>. The
+// encoder must be closed to flush it.
+func NewEncoder(name string, w io.Writer, wrap int) (io.WriteCloser, error) {
+	err := Check(name)
+	if err != nil {
+		return nil, err
+	}
+	if name == QP {
+		// Binary mode keeps line endings intact.
+		encoder := quotedprintable.NewWriter(w)
+		encoder.Binary = true
+		return encoder, nil
+	}
+	var wrapper *lineWriter
+	if wrap > 0 {
+		wrapper = &lineWriter{w: w, wrap: wrap}
+		w = wrapper
+	}
+	var encoder io.WriteCloser
+	switch name {
+	case Base64:
+		encoder = base64.NewEncoder(base64.StdEncoding, w)
+	case Base64Raw:
+		encoder = base64.NewEncoder(base64.RawStdEncoding, w)
+	case Base64URL:
+		encoder = base64.NewEncoder(base64.URLEncoding, w)
+	case Base64URLRaw:
+		encoder = base64.NewEncoder(base64.RawURLEncoding, w)
+	case Base32:
+		encoder = base32.NewEncoder(base32.StdEncoding, w)
+	case Hex:
+		encoder = nopCloser{hex.NewEncoder(w)}
+	case ASCII85:
+		encoder = &adobeWriter{WriteCloser: ascii85.NewEncoder(w), w: w}
+	case URL:
+		encoder = nopCloser{&percentWriter{w: w}}
+	}
+	if wrapper == nil {
+		return encoder, nil
+	}
+	return &wrappedEncoder{WriteCloser: encoder, wrapper: wrapper}, nil
+}
+
+// NewDecoder returns a reader decoding r with the codec name. Line breaks
+// are ignored, and with ignoreGarbage all characters not used by the codec.
+// Base64 padding is optional.
+func NewDecoder(name string, r io.Reader, ignoreGarbage bool) (io.Reader, error) {
+	err := Check(name)
+	if err != nil {
+		return nil, err
+	}
+	// keep returns the filter keeping the characters of alphabet, or only
+	// dropping whitespace unless garbage is ignored.
+	keep := func(alphabet string) func(byte) bool {
+		if ignoreGarbage {
+			return func(c byte) bool { return strings.IndexByte(alphabet, c) >= 0 }
+		}
+		return func(c byte) bool { return !isSpace(c) }
+	}
+	// Padding is dropped so padded and unpadded input decode alike.
+	noPadding := func(alphabet string) func(byte) bool {
+		k := keep(alphabet)
+		return func(c byte) bool { return c != '=' && k(c) }
+	}
+	switch name {
+	case Base64, Base64Raw:
+		return base64.NewDecoder(base64.RawStdEncoding, &filterReader{r: r, keep: noPadding(base64Alphabet)}), nil
+	case Base64URL, Base64URLRaw:
+		return base64.NewDecoder(base64.RawURLEncoding, &filterReader{r: r, keep: noPadding(base64URLAlphabet)}), nil
+	case Base32:
+		return base32.NewDecoder(base32.StdEncoding, &filterReader{r: r, keep: keep(base32Alphabet)}), nil
+	case Hex:
+		return hex.NewDecoder(&filterReader{r: r, keep: keep(hexAlphabet)}), nil
+	case ASCII85:
+		return ascii85.NewDecoder(&ascii85Reader{r: bufio.NewReader(&filterReader{r: r, keep: keep(ascii85Alphabet + "<~>")})}), nil
+	case URL:
+		return &percentReader{r: bufio.NewReader(&filterReader{r: r, keep: func(c byte) bool { return c != '\n' && c != '\r' }})}, nil
+	default:
+		return quotedprintable.NewReader(r), nil
+	}
+}
+
+// Detect guesses the codec of sample, the start of encoded data. Data that
+// is valid in several codecs is attributed to the one with the smallest
+// alphabet, so uppercase only base64 is taken for base32. Ascii85 is only
+// detected with its <~ delimiter. Data with whitespace other than line breaks
+// is taken for text rather than base16, base32 or base64.
+func Detect(sample []byte) (string, error) {
+	data := bytes.Map(func(r rune) rune {
+		if r < 0x80 && isSpace(byte(r)) {
+			return -1
+		}
+		return r
+	}, sample)
+	lines := !bytes.ContainsAny(sample, " \t\v\f")
+	only := func(alphabet string) bool {
+		for _, c := range data {
+			if strings.IndexByte(alphabet, c) < 0 {
+				return false
+			}
+		}
+		return lines
+	}
+	switch {
+	case len(data) == 0:
+		return "", errors.New("no data to detect the codec of")
+	case bytes.HasPrefix(data, []byte("<~")):
+		return ASCII85, nil
+	case only(hexAlphabet):
+		return Hex, nil
+	case only(base32Alphabet) && trailingPadding(data):
+		return Base32, nil
+	case only(base64Alphabet+"=") && trailingPadding(data):
+		return Base64, nil
+	case only(base64URLAlphabet+"=") && trailingPadding(data):
+		return Base64URL, nil
+	// Soft line breaks are checked first as quoted-printable keeps percent
+	// signs of the data.
+	case bytes.Contains(sample, []byte("=\n")) || bytes.Contains(sample, []byte("=\r\n")):
+		return QP, nil
+	case percentPattern(data, '%'):
+		return URL, nil
+	case percentPattern(sample, '='):
+		return QP, nil
+	}
+	return "", errors.New("unable to detect the codec, it needs to be given")
+}
+
+// trailingPadding reports whether data only has padding at its end.
+func trailingPadding(data []byte) bool {
+	return !bytes.Contains(bytes.TrimRight(data, "="), []byte("="))
+}
+
+// percentPattern reports whether data contains escape followed by two hex
+// digits.
+func percentPattern(data []byte, escape byte) bool {
+	for i := 0; i+2 < len(data); i++ {
+		if data[i] == escape && isHex(data[i+1]) && isHex(data[i+2]) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewDetectingDecoder detects the codec from the start of r and returns the
+// decoder for it along with its name.
+func NewDetectingDecoder(r io.Reader, ignoreGarbage bool) (io.Reader, string, error) {
+	reader := bufio.NewReaderSize(r, 4096)
+	sample, err := reader.Peek(4096)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, "", fmt.Errorf("failed to read input: %w", err)
+	}
+	name, err := Detect(sample)
+	if err != nil {
+		return nil, "", err
+	}
+	decoder, err := NewDecoder(name, reader, ignoreGarbage)
+	return decoder, name, err
+}
+
+// EncodeString encodes s with the codec name, without wrapping.
+func EncodeString(name, s string) (string, error) {
+	var b strings.Builder
+	encoder, err := NewEncoder(name, &b, 0)
+	if err != nil {
+		return "", err
+	}
+	_, err = encoder.Write([]byte(s))
+	if err == nil {
+		err = encoder.Close()
+	}
+	return b.String(), err
+}
+
+// DecodeString decodes s with the codec name, or the detected one if name
+// is empty.
+func DecodeString(name, s string) (string, error) {
+	var decoder io.Reader
+	var err error
+	if name == "" {
+		decoder, _, err = NewDetectingDecoder(strings.NewReader(s), false)
+	} else {
+		decoder, err = NewDecoder(name, strings.NewReader(s), false)
+	}
+	if err != nil {
+		return "", err
+	}
+	decoded, err := io.ReadAll(decoder)
+	return string(decoded), err
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
+
+func isHex(c byte) bool {
+	return strings.IndexByte(hexAlphabet, c) >= 0
+}
@@ -0,0 +1,120 @@
+package codec
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundTrip(t *testing.T) {
+	data := []byte("hello, wörld! \x00\xff\n= 100% ~>")
+	for _, name := range Names {
+		var encoded bytes.Buffer
+		encoder, err := NewEncoder(name, &encoded, 8)
+		require.NoError(t, err, name)
+		// Write in small chunks to exercise the streaming.
+		for i := 0; i < len(data); i += 3 {
+			_, err = encoder.Write(data[i:min(i+3, len(data))])
+			require.NoError(t, err, name)
+		}
+		require.NoError(t, encoder.Close(), name)
+
+		decoder, err := NewDecoder(name, bytes.NewReader(encoded.Bytes()), false)
+		require.NoError(t, err, name)
+		decoded, err := io.ReadAll(decoder)
+		require.NoError(t, err, name)
+		assert.Equal(t, data, decoded, name)
+	}
+}
+
+func TestEncodeWrap(t *testing.T) {
+	var out bytes.Buffer
+	encoder, err := NewEncoder(Base64, &out, 4)
+	require.NoError(t, err)
+	_, err = encoder.Write([]byte("abcdefg"))
+	require.NoError(t, err)
+	require.NoError(t, encoder.Close())
+	assert.Equal(t, "YWJj\nZGVm\nZw==\n", out.String())
+
+	encoded, err := EncodeString(URL, "a b/ü")
+	require.NoError(t, err)
+	assert.Equal(t, "a%20b%2F%C3%BC", encoded)
+}
+
+func TestDecode(t *testing.T) {
+	for _, test := range []struct {
+		name, input, expected string
+	}{
+		{Base64, "Zm9vYg", "foob"},
+		{Base64Raw, "Zm9vYg==", "foob"},
+		{Base64URL, "_-8", "\xff\xef"},
+		{Hex, "66 6f\n6f", "foo"},
+		{ASCII85, "<~AoDS~>", "foo"},
+		{QP, "caf=C3=A9 =\nok", "café ok"},
+	} {
+		decoded, err := DecodeString(test.name, test.input)
+		require.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, decoded, test.name)
+	}
+
+	decoder, err := NewDecoder(Base64, strings.NewReader("Zm9v*YmFy!"), false)
+	require.NoError(t, err)
+	_, err = io.ReadAll(decoder)
+	assert.Error(t, err)
+	decoder, err = NewDecoder(Base64, strings.NewReader("Zm9v*YmFy!"), true)
+	require.NoError(t, err)
+	decoded, err := io.ReadAll(decoder)
+	require.NoError(t, err)
+	assert.Equal(t, "foobar", string(decoded))
+}
+
+func TestDetect(t *testing.T) {
+	for input, expected := range map[string]string{
+		"666f6f\n":     Hex,
+		"MZXW6===":     Base32,
+		"Zm9vYmFy+/==": Base64,
+		"Zm9vYmFy-_":   Base64URL,
+		"a%20b":        URL,
+		"caf=C3=A9":    QP,
+		"<~AoDS~>":     ASCII85,
+	} {
+		name, err := Detect([]byte(input))
+		require.NoError(t, err, input)
+		assert.Equal(t, expected, name, input)
+	}
+	decoded, err := DecodeString("", "Zm9vYmFy\n")
+	require.NoError(t, err)
+	assert.Equal(t, "foobar", decoded)
+
+	for _, input := range []string{"hello world", "9jqo^BlbD-BleB", "caf\u00e9"} {
+		_, err = DecodeString("", input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestDetectRoundTrip(t *testing.T) {
+	data := make([]byte, 5000)
+	_, err := rand.New(rand.NewSource(1)).Read(data)
+	require.NoError(t, err)
+	for _, name := range Names {
+		for _, wrap := range []int{0, 76} {
+			var encoded bytes.Buffer
+			encoder, err := NewEncoder(name, &encoded, wrap)
+			require.NoError(t, err, name)
+			_, err = encoder.Write(data)
+			require.NoError(t, err, name)
+			require.NoError(t, encoder.Close(), name)
+
+			decoder, _, err := NewDetectingDecoder(&encoded, false)
+			require.NoError(t, err, name)
+			decoded, err := io.ReadAll(decoder)
+			require.NoError(t, err, name)
+			assert.Equal(t, data, decoded, "%s wrapped at %d", name, wrap)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package codec
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// lineWriter breaks what is written to it into lines of wrap characters.
+type lineWriter struct {
+	w      io.Writer
+	wrap   int
+	column int
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := l.wrap - l.column
+		if n > len(p) {
+			n = len(p)
+		}
+		m, err := l.w.Write(p[:n])
+		written += m
+		l.column += m
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+		if l.column == l.wrap {
+			_, err = l.w.Write([]byte{'\n'})
+			if err != nil {
+				return written, err
+			}
+			l.column = 0
+		}
+	}
+	return written, nil
+}
+
+// wrappedEncoder ends the last line of a wrapped encoder when closed.
+type wrappedEncoder struct {
+	io.WriteCloser
+	wrapper *lineWriter
+}
+
+func (e *wrappedEncoder) Close() error {
+	err := e.WriteCloser.Close()
+	if err != nil || e.wrapper.column == 0 {
+		return err
+	}
+	_, err = e.wrapper.w.Write([]byte{'\n'})
+	return err
+}
+
+// adobeWriter delimits the output of an ascii85 encoder by <~ and ~>.
+type adobeWriter struct {
+	io.WriteCloser
+	w       io.Writer
+	started bool
+}
+
+func (a *adobeWriter) start() error {
+	if a.started {
+		return nil
+	}
+	a.started = true
+	_, err := a.w.Write([]byte("<~"))
+	return err
+}
+
+func (a *adobeWriter) Write(p []byte) (int, error) {
+	err := a.start()
+	if err != nil {
+		return 0, err
+	}
+	return a.WriteCloser.Write(p)
+}
+
+func (a *adobeWriter) Close() error {
+	err := a.start()
+	if err == nil {
+		err = a.WriteCloser.Close()
+	}
+	if err == nil {
+		_, err = a.w.Write([]byte("~>"))
+	}
+	return err
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// filterReader drops the bytes keep returns false for.
+type filterReader struct {
+	r    io.Reader
+	keep func(byte) bool
+}
+
+func (f *filterReader) Read(p []byte) (int, error) {
+	for {
+		n, err := f.r.Read(p)
+		kept := 0
+		for _, c := range p[:n] {
+			if f.keep(c) {
+				p[kept] = c
+				kept++
+			}
+		}
+		// Do not return zero bytes without an error for input that was
+		// dropped completely.
+		if kept > 0 || err != nil {
+			return kept, err
+		}
+	}
+}
+
+// percentWriter percent-encodes all bytes but the unreserved characters of
+// RFC 3986.
+type percentWriter struct {
+	w io.Writer
+}
+
+func (p *percentWriter) Write(data []byte) (int, error) {
+	encoded := make([]byte, 0, len(data))
+	for _, c := range data {
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~' {
+			encoded = append(encoded, c)
+		} else {
+			encoded = append(encoded, fmt.Sprintf("%%%02X", c)...)
+		}
+	}
+	_, err := p.w.Write(encoded)
+	if err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// percentReader decodes percent-encoded input. Other bytes, including
+// malformed escapes, are passed through.
+type percentReader struct {
+	r *bufio.Reader
+}
+
+func (p *percentReader) Read(data []byte) (int, error) {
+	n := 0
+	for n < len(data) {
+		c, err := p.r.ReadByte()
+		if err != nil {
+			if n > 0 && errors.Is(err, io.EOF) {
+				return n, nil
+			}
+			return n, err
+		}
+		if c == '%' {
+			if escape, err := p.r.Peek(2); err == nil && isHex(escape[0]) && isHex(escape[1]) {
+				c = unhex(escape[0])<<4 | unhex(escape[1])
+				_, _ = p.r.Discard(2)
+			}
+		}
+		data[n] = c
+		n++
+		if p.r.Buffered() == 0 {
+			break
+		}
+	}
+	return n, nil
+}
+
+func unhex(c byte) byte {
+	switch {
+	case c >= 'a':
+		return c - 'a' + 10
+	case c >= 'A':
+		return c - 'A' + 10
+	default:
+		return c - '0'
+	}
+}
+
+// ascii85Reader strips the <~ and ~> delimiters of Adobe ascii85.
+type ascii85Reader struct {
+	r       *bufio.Reader
+	started bool
+	done    bool
+}
+
+func (a *ascii85Reader) Read(p []byte) (int, error) {
+	if !a.started {
+		a.started = true
+		if prefix, err := a.r.Peek(2); err == nil && string(prefix) == "<~" {
+			_, _ = a.r.Discard(2)
+		}
+	}
+	n := 0
+	for n < len(p) && !a.done {
+		c, err := a.r.ReadByte()
+		if err != nil {
+			if n > 0 && errors.Is(err, io.EOF) {
+				return n, nil
+			}
+			return n, err
+		}
+		if c == '~' {
+			a.done = true
+			break
+		}
+		p[n] = c
+		n++
+		if a.r.Buffered() == 0 {
+			break
+		}
+	}
+	if n == 0 && a.done {
+		return 0, io.EOF
+	}
+	return n, nil
+}
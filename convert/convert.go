@@ -23,10 +23,10 @@ var inputFormats = map[string]inputFormatType{
 	"json":  jsonIn{},
 	"jsonl": jsonlIn{},
 	"regex": regexIn{},
-	//"yaml" : yamlIn{},
-	//toml" : tomlIn{},
+	"csv":   csvIn{},
+	"yaml":  yamlIn{},
+	"toml":  tomlIn{},
 	//"xml" : xmlIn{},
-	//"csv" : csvIn{},
 	//"ini" : iniIn{},
 	//"json5" : json5In{},
 	//hcl" : hclIn{},
@@ -39,8 +39,46 @@ var outputFormats = map[string]outputFormatType{
 	"jsonl": jsonlOut{},
 }
 
-// Convert converts data from one format to another.
-func Convert(from string, fromArgs []string, to string, toArgs []string, in, out *os.File) error {
+// Decode parses all of in using the input format from. Input of line by line
+// formats is returned as a slice of the parsed lines.
+func Decode(from string, fromArgs []string, in io.Reader) (interface{}, error) {
+	inputFormat, ok := inputFormats[from]
+	if !ok {
+		return nil, fmt.Errorf("invalid input format: %s", from)
+	}
+	err := inputFormat.init(fromArgs)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing input format: %w", err)
+	}
+	if !inputFormat.isLineByLine() {
+		input, err := io.ReadAll(in)
+		if err != nil {
+			return nil, fmt.Errorf("error reading input: %w", err)
+		}
+		inputData, err := inputFormat.convert(input)
+		if err != nil {
+			return nil, fmt.Errorf("error converting input: %w", err)
+		}
+		return inputData, nil
+	}
+	scanner := bufio.NewScanner(in)
+	lines := []interface{}{}
+	for scanner.Scan() {
+		lineData, err := inputFormat.convert([]byte(scanner.Text()))
+		if err != nil {
+			return nil, fmt.Errorf("error converting line in: %w", err)
+		}
+		lines = append(lines, lineData)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading input: %w", err)
+	}
+	return lines, nil
+}
+
+// Convert converts data from one format to another, applying the transforms
+// to every line of line by line formats or else to the whole input.
+func Convert(from string, fromArgs []string, to string, toArgs []string, transforms []Transform, in, out *os.File) error {
 	inputFormat, ok := inputFormats[from]
 	if !ok {
 		return fmt.Errorf("invalid input format: %s", from)
@@ -64,6 +102,10 @@ func Convert(from string, fromArgs []string, to string, toArgs []string, in, out
 			if err != nil {
 				return fmt.Errorf("error converting line in: %w", err)
 			}
+			lineData, err = applyTransforms(lineData, transforms)
+			if err != nil {
+				return err
+			}
 			outputData, err := outputFormat.convert(lineData)
 			if err != nil {
 				return fmt.Errorf("error converting line out: %w", err)
@@ -78,6 +120,10 @@ func Convert(from string, fromArgs []string, to string, toArgs []string, in, out
 			if err != nil {
 				return fmt.Errorf("error converting line in: %w", err)
 			}
+			lineData, err = applyTransforms(lineData, transforms)
+			if err != nil {
+				return err
+			}
 			lines = append(lines, lineData)
 		}
 		outputData, err := outputFormat.convert(lines)
@@ -94,6 +140,10 @@ func Convert(from string, fromArgs []string, to string, toArgs []string, in, out
 		if err != nil {
 			return fmt.Errorf("error converting input: %w", err)
 		}
+		lines, err = applyTransforms(lines, transforms)
+		if err != nil {
+			return err
+		}
 		// TODO check if is array, if not error
 		for _, line := range lines.([]interface{}) {
 			outputData, err := outputFormat.convert(line)
@@ -112,6 +162,10 @@ func Convert(from string, fromArgs []string, to string, toArgs []string, in, out
 		if err != nil {
 			return fmt.Errorf("error converting input: %w", err)
 		}
+		inputData, err = applyTransforms(inputData, transforms)
+		if err != nil {
+			return err
+		}
 		outputData, err := outputFormat.convert(inputData)
 		if err != nil {
 			return fmt.Errorf("error converting output: %w", err)
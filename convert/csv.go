@@ -0,0 +1,48 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+)
+
+type csvIn struct {
+}
+
+func (c csvIn) isLineByLine() bool {
+	return false
+}
+
+// convert returns one object per record, keyed by the names in the header
+// row.
+func (c csvIn) convert(data []byte) (interface{}, error) {
+	// Spreadsheet applications like to start exports with a byte order mark.
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("missing header row")
+	}
+	header := records[0]
+	result := make([]interface{}, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]interface{}, len(header))
+		for i, name := range header {
+			if i < len(record) {
+				row[name] = record[i]
+			} else {
+				row[name] = ""
+			}
+		}
+		result = append(result, row)
+	}
+	return result, nil
+}
+
+func (c csvIn) init(_ []string) error {
+	return nil
+}
@@ -0,0 +1,32 @@
+package regex2json
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodecOperators(t *testing.T) {
+	op, err := NewOperator("json___decode__base64")
+	require.NoError(t, err)
+	out, err := op("eyJhIjoiYiJ9")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"a": "b"}, out)
+
+	op, err = NewOperator("encode__hex")
+	require.NoError(t, err)
+	out, err = op("hi")
+	require.NoError(t, err)
+	assert.Equal(t, "6869", out)
+
+	_, err = NewOperator("encode__rot13")
+	assert.ErrorContains(t, err, "unknown codec")
+
+	exp, err := NewExpression("foo___decode")
+	require.NoError(t, err)
+	output := map[string]any{}
+	require.NoError(t, exp.Apply(output, "a%2Fb"))
+	assert.Equal(t, map[string]any{"foo": "a/b"}, output)
+	assert.ErrorIs(t, exp.Apply(output, "hello world"), ErrInvalidValue, "plain text is not decoded")
+}
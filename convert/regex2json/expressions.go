@@ -10,6 +10,7 @@ import (
 	"github.com/tkuchiki/go-timezone"
 	"strconv"
 	"strings"
+	"tasadar.net/tionis/shell-tools/codec"
 	"time"
 )
 
@@ -394,6 +395,64 @@ func JSONOperator(args ...string) (Op, error) {
 	}, nil
 }
 
+// EncodeOperator returns the encode operator which encodes input string
+// with a codec of [codec.Names].
+//
+// It expects the codec name as the only argument.
+func EncodeOperator(args ...string) (Op, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("%w: expected codec name", ErrMissingArgument)
+	}
+	name := args[0]
+	err := codec.Check(name)
+	if err != nil {
+		return nil, err
+	}
+	return func(in any) (any, error) {
+		s, skip, err := toStringOrSkip(in)
+		if err != nil {
+			return nil, err
+		}
+		if skip {
+			return in, nil
+		}
+		return codec.EncodeString(name, s)
+	}, nil
+}
+
+// DecodeOperator returns the decode operator which decodes input string
+// with a codec of [codec.Names].
+//
+// It expects the codec name as an optional argument, the codec is detected
+// without it.
+func DecodeOperator(args ...string) (Op, error) {
+	if len(args) > 1 {
+		return nil, fmt.Errorf("%w: %s", ErrUnexpectedArgument, strings.Join(args[1:], ", "))
+	}
+	name := ""
+	if len(args) == 1 {
+		name = args[0]
+		err := codec.Check(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return func(in any) (any, error) {
+		s, skip, err := toStringOrSkip(in)
+		if err != nil {
+			return nil, err
+		}
+		if skip {
+			return in, nil
+		}
+		decoded, err := codec.DecodeString(name, s)
+		if err != nil {
+			return nil, fmt.Errorf(`%w: unable to decode "%s": %w`, ErrInvalidValue, s, err)
+		}
+		return decoded, nil
+	}, nil
+}
+
 // Library is a map of all supported operators.
 var Library = map[string]func(args ...string) (Op, error){ //nolint:gochecknoglobals
 	"int":      IntOperator,
@@ -405,6 +464,8 @@ var Library = map[string]func(args ...string) (Op, error){ //nolint:gochecknoglo
 	"object":   ObjectOperator,
 	"time":     TimeOperator,
 	"json":     JSONOperator,
+	"encode":   EncodeOperator,
+	"decode":   DecodeOperator,
 }
 
 // Expression is a compiled expression which can be applied on a value
@@ -557,6 +618,33 @@ func (s Expression) String() string {
 	return s.expression
 }
 
+// NewOperator compiles a series of operators, with the syntax of an
+// [Expression] but without the implicit object operator, into a single
+// operator. Like in expressions, the last operator is called first.
+//
+// Example:
+//
+//	json___decode__base64
+func NewOperator(chain string) (Op, error) {
+	if chain == "" {
+		return nil, ErrEmptyExpression
+	}
+	fns, err := compileOperators(strings.Split(chain, "___"), "operators", chain)
+	if err != nil {
+		return nil, err
+	}
+	return func(in any) (any, error) {
+		var err error
+		for _, f := range fns {
+			in, err = f(in)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return in, nil
+	}, nil
+}
+
 // NewExpression compiles the expression into the Expression.
 func NewExpression(expression string) (*Expression, error) {
 	if expression == "" {
@@ -565,7 +653,6 @@ func NewExpression(expression string) (*Expression, error) {
 
 	res := &Expression{
 		expression: expression,
-		fns:        make([]Op, 0),
 	}
 
 	chain := strings.Split(expression, "___")
@@ -579,22 +666,36 @@ func NewExpression(expression string) (*Expression, error) {
 		chain[0] = "object__" + chain[0]
 	}
 
+	fns, err := compileOperators(chain, "expression", expression)
+	if err != nil {
+		return nil, err
+	}
+	res.fns = fns
+
+	return res, nil
+}
+
+// compileOperators compiles the operators of chain, each an operator name
+// followed by its arguments separated by __, in reverse order, so that they
+// are called from the last to the first. Errors name the source of the chain,
+// which is described by kind.
+func compileOperators(chain []string, kind, source string) ([]Op, error) {
+	fns := make([]Op, 0, len(chain))
 	for _, c := range chain {
 		if c == "" {
-			return nil, fmt.Errorf(`%w: expression "%s"`, ErrEmptyOperator, expression)
+			return nil, fmt.Errorf(`%w: %s "%s"`, ErrEmptyOperator, kind, source)
 		}
 		ops := strings.Split(c, "__")
 		functor, ok := Library[ops[0]]
 		if !ok {
-			return nil, fmt.Errorf(`%w: "%s" for expression "%s"`, ErrInvalidOperator, ops[0], expression)
+			return nil, fmt.Errorf(`%w: "%s" for %s "%s"`, ErrInvalidOperator, ops[0], kind, source)
 		}
 		f, err := functor(ops[1:]...)
 		if err != nil {
-			return nil, fmt.Errorf(`%w: "%s" for expression "%s": %w`, ErrCompilingOperator, ops[0], expression, err)
+			return nil, fmt.Errorf(`%w: "%s" for %s "%s": %w`, ErrCompilingOperator, ops[0], kind, source, err)
 		}
 		// We prepend the new operator, so that in Apply we call from the last to the first operator.
-		res.fns = append([]Op{f}, res.fns...)
+		fns = append([]Op{f}, fns...)
 	}
-
-	return res, nil
+	return fns, nil
 }
@@ -0,0 +1,25 @@
+package convert
+
+import (
+	"github.com/BurntSushi/toml"
+)
+
+type tomlIn struct {
+}
+
+func (t tomlIn) isLineByLine() bool {
+	return false
+}
+
+func (t tomlIn) convert(data []byte) (interface{}, error) {
+	var result map[string]interface{}
+	err := toml.Unmarshal(data, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (t tomlIn) init(_ []string) error {
+	return nil
+}
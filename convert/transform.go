@@ -0,0 +1,81 @@
+package convert
+
+import (
+	"fmt"
+	"strings"
+	"tasadar.net/tionis/shell-tools/convert/regex2json"
+)
+
+// Transform applies regex2json operators to a field of converted data.
+type Transform struct {
+	path []string
+	op   regex2json.Op
+}
+
+// ParseTransform parses a transform given as path=operators. The path is a
+// dot separated list of fields, operators use the syntax of
+// [regex2json.NewOperator], e.g. payload=json___decode__base64. An empty
+// path transforms the value itself.
+func ParseTransform(s string) (Transform, error) {
+	path, operators, ok := strings.Cut(s, "=")
+	if !ok {
+		return Transform{}, fmt.Errorf("invalid transform %q, expected path=operators", s)
+	}
+	op, err := regex2json.NewOperator(operators)
+	if err != nil {
+		return Transform{}, fmt.Errorf("invalid transform %q: %w", s, err)
+	}
+	var fields []string
+	if path != "" {
+		fields = strings.Split(path, ".")
+	}
+	return Transform{path: fields, op: op}, nil
+}
+
+// Apply transforms the field of data at the path of the transform. Arrays
+// on the way are transformed element by element, missing fields are left
+// alone.
+func (t Transform) Apply(data interface{}) (interface{}, error) {
+	return t.apply(data, t.path)
+}
+
+func (t Transform) apply(data interface{}, path []string) (interface{}, error) {
+	if list, ok := data.([]interface{}); ok {
+		for i, element := range list {
+			transformed, err := t.apply(element, path)
+			if err != nil {
+				return nil, fmt.Errorf("%d: %w", i, err)
+			}
+			list[i] = transformed
+		}
+		return list, nil
+	}
+	if len(path) == 0 {
+		return t.op(data)
+	}
+	object, ok := data.(map[string]interface{})
+	if !ok {
+		return data, nil
+	}
+	value, ok := object[path[0]]
+	if !ok {
+		return data, nil
+	}
+	transformed, err := t.apply(value, path[1:])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path[0], err)
+	}
+	object[path[0]] = transformed
+	return object, nil
+}
+
+func applyTransforms(data interface{}, transforms []Transform) (interface{}, error) {
+	var err error
+	for _, transform := range transforms {
+		data, err = transform.Apply(data)
+		if err != nil {
+			return nil, fmt.Errorf("error transforming data: %w", err)
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,30 @@
+package convert
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransform(t *testing.T) {
+	transform, err := ParseTransform("items.data=decode__base64")
+	require.NoError(t, err)
+	data := map[string]interface{}{"items": []interface{}{
+		map[string]interface{}{"data": "aGk="},
+		map[string]interface{}{"other": 1},
+	}}
+	out, err := transform.Apply(data)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"items": []interface{}{
+		map[string]interface{}{"data": "hi"},
+		map[string]interface{}{"other": 1},
+	}}, out)
+
+	_, err = ParseTransform("data")
+	assert.ErrorContains(t, err, "expected path=operators")
+	transform, err = ParseTransform("=int")
+	require.NoError(t, err)
+	_, err = transform.Apply([]interface{}{"1", "x"})
+	assert.ErrorContains(t, err, "1: invalid value")
+}
@@ -0,0 +1,25 @@
+package convert
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+type yamlIn struct {
+}
+
+func (y yamlIn) isLineByLine() bool {
+	return false
+}
+
+func (y yamlIn) convert(data []byte) (interface{}, error) {
+	var result interface{}
+	err := yaml.Unmarshal(data, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (y yamlIn) init(_ []string) error {
+	return nil
+}
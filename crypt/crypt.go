@@ -0,0 +1,223 @@
+// Package crypt implements streaming age encryption and decryption with
+// X25519, SSH and passphrase keys.
+package crypt
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+	"filippo.io/age/armor"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ParseRecipient parses an age X25519 recipient or an SSH public key.
+func ParseRecipient(s string) (age.Recipient, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "ssh-") {
+		recipient, err := agessh.ParseRecipient(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ssh recipient %q: %w", s, err)
+		}
+		return recipient, nil
+	}
+	return age.ParseX25519Recipient(s)
+}
+
+// ParseRecipients parses recipients one per line. Empty lines and lines
+// starting with # are ignored.
+func ParseRecipients(r io.Reader) ([]age.Recipient, error) {
+	var recipients []age.Recipient
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		recipient, err := ParseRecipient(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		recipients = append(recipients, recipient)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return recipients, nil
+}
+
+// ReadRecipientsFile parses the recipients file at path.
+func ReadRecipientsFile(path string) ([]age.Recipient, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recipients file: %w", err)
+	}
+	defer file.Close()
+	recipients, err := ParseRecipients(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse recipients file %s: %w", path, err)
+	}
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("no recipients in %s", path)
+	}
+	return recipients, nil
+}
+
+// isSSHKey reports whether data is a PEM encoded SSH private key.
+func isSSHKey(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN"))
+}
+
+// ParseIdentities parses an identity file, which is either a list of age
+// X25519 identities or an SSH private key. Passphrase protected SSH keys
+// ask passphrase once they are needed.
+func ParseIdentities(data []byte, passphrase func() (string, error)) ([]age.Identity, error) {
+	if !isSSHKey(data) {
+		return age.ParseIdentities(bytes.NewReader(data))
+	}
+	identity, err := agessh.ParseIdentity(data)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		if missing.PublicKey == nil {
+			return nil, errors.New("encrypted ssh key without public key")
+		}
+		identity, err = agessh.NewEncryptedSSHIdentity(missing.PublicKey, data, func() ([]byte, error) {
+			if passphrase == nil {
+				return nil, errors.New("ssh key is encrypted, but no passphrase can be asked for")
+			}
+			secret, err := passphrase()
+			return []byte(secret), err
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []age.Identity{identity}, nil
+}
+
+// ReadIdentityFile parses the identity file at path.
+func ReadIdentityFile(path string, passphrase func() (string, error)) ([]age.Identity, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identity file: %w", err)
+	}
+	identities, err := ParseIdentities(data, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse identity file %s: %w", path, err)
+	}
+	return identities, nil
+}
+
+// Recipients returns the public keys of the identities in an identity file,
+// in the form accepted by ParseRecipient.
+func Recipients(data []byte) ([]string, error) {
+	if isSSHKey(data) {
+		signer, err := ssh.ParsePrivateKey(data)
+		var missing *ssh.PassphraseMissingError
+		switch {
+		case errors.As(err, &missing) && missing.PublicKey != nil:
+			return []string{sshRecipient(missing.PublicKey)}, nil
+		case err != nil:
+			return nil, err
+		}
+		return []string{sshRecipient(signer.PublicKey())}, nil
+	}
+	identities, err := age.ParseIdentities(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	recipients := make([]string, 0, len(identities))
+	for _, identity := range identities {
+		x25519, ok := identity.(*age.X25519Identity)
+		if !ok {
+			return nil, fmt.Errorf("unsupported identity type %T", identity)
+		}
+		recipients = append(recipients, x25519.Recipient().String())
+	}
+	return recipients, nil
+}
+
+func sshRecipient(key ssh.PublicKey) string {
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+}
+
+// GenerateIdentity returns a new X25519 identity formatted as identity
+// file, and its recipient.
+func GenerateIdentity() ([]byte, string, error) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate identity: %w", err)
+	}
+	recipient := identity.Recipient().String()
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# created: %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&buf, "# public key: %s\n", recipient)
+	fmt.Fprintf(&buf, "%s\n", identity)
+	return buf.Bytes(), recipient, nil
+}
+
+// Encrypt streams src encrypted to recipients into dst, optionally ASCII
+// armored.
+func Encrypt(dst io.Writer, src io.Reader, recipients []age.Recipient, armored bool) error {
+	var armorWriter io.WriteCloser
+	if armored {
+		armorWriter = armor.NewWriter(dst)
+		dst = armorWriter
+	}
+	w, err := age.Encrypt(dst, recipients...)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt: %w", err)
+	}
+	_, err = io.Copy(w, src)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt: %w", err)
+	}
+	err = w.Close()
+	if err != nil {
+		return fmt.Errorf("failed to encrypt: %w", err)
+	}
+	if armorWriter != nil {
+		return armorWriter.Close()
+	}
+	return nil
+}
+
+// Decrypt streams src, which may be ASCII armored, decrypted with one of
+// identities into dst.
+func Decrypt(dst io.Writer, src io.Reader, identities []age.Identity) error {
+	r, err := decryptReader(src, identities)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, r)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return nil
+}
+
+// IsArmored reports whether data, or its beginning, is an ASCII armored age
+// file. Armored files may start with whitespace, which age itself accepts.
+func IsArmored(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte(armor.Header))
+}
+
+func decryptReader(src io.Reader, identities []age.Identity) (io.Reader, error) {
+	buffered := bufio.NewReader(src)
+	start, _ := buffered.Peek(len(armor.Header) + 64)
+	src = buffered
+	if IsArmored(start) {
+		src = armor.NewReader(buffered)
+	}
+	r, err := age.Decrypt(src, identities...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return r, nil
+}
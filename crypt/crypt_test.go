@@ -0,0 +1,77 @@
+package crypt
+
+import (
+	"bytes"
+	"filippo.io/age"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptDecryptX25519(t *testing.T) {
+	identityFile, recipient, err := GenerateIdentity()
+	require.NoError(t, err)
+	recipients, err := ParseRecipients(strings.NewReader("# comment\n\n" + recipient + "\n"))
+	require.NoError(t, err)
+	identities, err := ParseIdentities(identityFile, nil)
+	require.NoError(t, err)
+	derived, err := Recipients(identityFile)
+	require.NoError(t, err)
+	assert.Equal(t, []string{recipient}, derived)
+
+	for _, armored := range []bool{false, true} {
+		var encrypted, decrypted bytes.Buffer
+		require.NoError(t, Encrypt(&encrypted, strings.NewReader("secret"), recipients, armored))
+		assert.Equal(t, armored, IsArmored(encrypted.Bytes()))
+		require.NoError(t, Decrypt(&decrypted, &encrypted, identities))
+		assert.Equal(t, "secret", decrypted.String())
+	}
+}
+
+// sshKey generates an ed25519 key with ssh-keygen, which is the only way to
+// get keys in the OpenSSH format with the vendored x/crypto.
+func sshKey(t *testing.T, passphrase string) []byte {
+	t.Helper()
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not found")
+	}
+	path := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", passphrase, "-f", path).Run())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return data
+}
+
+func TestEncryptDecryptSSH(t *testing.T) {
+	for _, passphrase := range []string{"", "hunter2"} {
+		privateKey := sshKey(t, passphrase)
+		public, err := Recipients(privateKey)
+		require.NoError(t, err)
+		require.Len(t, public, 1)
+		assert.True(t, strings.HasPrefix(public[0], "ssh-ed25519 "))
+		recipient, err := ParseRecipient(public[0])
+		require.NoError(t, err)
+		asked := false
+		identities, err := ParseIdentities(privateKey, func() (string, error) {
+			asked = true
+			return passphrase, nil
+		})
+		require.NoError(t, err)
+
+		var encrypted, decrypted bytes.Buffer
+		require.NoError(t, Encrypt(&encrypted, strings.NewReader("secret"), []age.Recipient{recipient}, true))
+		require.NoError(t, Decrypt(&decrypted, &encrypted, identities))
+		assert.Equal(t, "secret", decrypted.String())
+		assert.Equal(t, passphrase != "", asked)
+	}
+}
+
+func TestParseRecipientsInvalid(t *testing.T) {
+	_, err := ParseRecipients(strings.NewReader("age1invalid\n"))
+	assert.ErrorContains(t, err, "line 1")
+}
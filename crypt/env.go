@@ -0,0 +1,128 @@
+package crypt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Formats of environment files.
+const (
+	FormatDotenv = "dotenv"
+	FormatJSON   = "json"
+	FormatYAML   = "yaml"
+)
+
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// EnvFormat guesses the format of an environment file from its name,
+// ignoring a trailing .age extension. Unknown names are taken as dotenv.
+func EnvFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(strings.TrimSuffix(path, ".age"))) {
+	case ".json":
+		return FormatJSON
+	case ".yaml", ".yml":
+		return FormatYAML
+	default:
+		return FormatDotenv
+	}
+}
+
+// ParseEnv parses variables from data in the given format. JSON and YAML
+// documents must be objects; values that are not strings, numbers or
+// booleans are passed on JSON encoded.
+func ParseEnv(data []byte, format string) (map[string]string, error) {
+	var values map[string]interface{}
+	var err error
+	switch format {
+	case FormatDotenv:
+		return parseDotenv(data)
+	case FormatJSON:
+		// Numbers are kept as written instead of going through float64.
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&values)
+	case FormatYAML:
+		err = yaml.Unmarshal(data, &values)
+	default:
+		return nil, fmt.Errorf("unknown env format: %s", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s env: %w", format, err)
+	}
+	env := make(map[string]string, len(values))
+	for key, value := range values {
+		if !envName.MatchString(key) {
+			return nil, fmt.Errorf("invalid variable name: %q", key)
+		}
+		switch value := value.(type) {
+		case string:
+			env[key] = value
+		case json.Number:
+			env[key] = value.String()
+		case nil:
+			env[key] = ""
+		case float64:
+			env[key] = strconv.FormatFloat(value, 'f', -1, 64)
+		case bool, int:
+			env[key] = fmt.Sprint(value)
+		default:
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode %s: %w", key, err)
+			}
+			env[key] = string(encoded)
+		}
+	}
+	return env, nil
+}
+
+// parseDotenv parses KEY=value lines. Lines may start with "export", values
+// may be single quoted to be taken literally or double quoted to interpret
+// escapes like \n. Unquoted values end at " #".
+func parseDotenv(data []byte) (map[string]string, error) {
+	env := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !envName.MatchString(key) {
+			return nil, fmt.Errorf("line %d: expected KEY=value", n)
+		}
+		value = strings.TrimSpace(value)
+		switch {
+		case strings.HasPrefix(value, "'"):
+			end := strings.Index(value[1:], "'")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated quote", n)
+			}
+			value = value[1 : end+1]
+		case strings.HasPrefix(value, `"`):
+			quoted, err := strconv.QuotedPrefix(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid double quoted value", n)
+			}
+			value, _ = strconv.Unquote(quoted)
+		default:
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+		env[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
@@ -0,0 +1,47 @@
+package crypt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEnvDotenv(t *testing.T) {
+	env, err := ParseEnv([]byte("# comment\n"+
+		"export A=plain # trailing\n"+
+		"B='single $quoted'\n"+
+		"C=\"double\\nquoted\"\n"+
+		"D=\n"+
+		"E=a#b\n"), FormatDotenv)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"A": "plain",
+		"B": "single $quoted",
+		"C": "double\nquoted",
+		"D": "",
+		"E": "a#b",
+	}, env)
+
+	_, err = ParseEnv([]byte("not a variable\n"), FormatDotenv)
+	assert.ErrorContains(t, err, "line 1")
+}
+
+func TestParseEnvStructured(t *testing.T) {
+	env, err := ParseEnv([]byte(`{"PORT": 12345678, "DEBUG": true, "LIST": [1, 2], "EMPTY": null}`), FormatJSON)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"PORT": "12345678", "DEBUG": "true", "LIST": "[1,2]", "EMPTY": ""}, env)
+
+	env, err = ParseEnv([]byte("PORT: 8080\nRATIO: 0.5\nNAME: app\n"), FormatYAML)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"PORT": "8080", "RATIO": "0.5", "NAME": "app"}, env)
+
+	_, err = ParseEnv([]byte(`{"not-valid": "x"}`), FormatJSON)
+	assert.Error(t, err)
+}
+
+func TestEnvFormat(t *testing.T) {
+	assert.Equal(t, FormatDotenv, EnvFormat("secrets.env.age"))
+	assert.Equal(t, FormatJSON, EnvFormat("secrets.json.age"))
+	assert.Equal(t, FormatYAML, EnvFormat("secrets.yml"))
+}
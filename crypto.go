@@ -1,32 +1,547 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"filippo.io/age"
+	"fmt"
+	"github.com/urfave/cli/v2"
 	"io"
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/interp"
+	"mvdan.cc/sh/v3/syntax"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
+	"tasadar.net/tionis/shell-tools/crypt"
+	"tasadar.net/tionis/shell-tools/edit"
+	"tasadar.net/tionis/shell-tools/pass"
 )
 
-func decryptFile(filePath string) (string, error) {
-	file, err := os.Open(filePath)
+func cryptCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "crypt",
+		Usage: "encrypt and decrypt files with age",
+		Flags: []cli.Flag{
+			&cli.PathFlag{
+				Name:      "identities",
+				Usage:     "default age identity file (default: <user config dir>/shell-tools/identities)",
+				EnvVars:   []string{"SHELL_TOOLS_AGE_IDENTITIES"},
+				TakesFile: true,
+			},
+		},
+		Subcommands: []*cli.Command{
+			{
+				Name:      "encrypt",
+				Aliases:   []string{"e"},
+				Usage:     "encrypt a file or stdin to recipients or with a passphrase",
+				ArgsUsage: "[file]",
+				Flags: append([]cli.Flag{
+					&cli.BoolFlag{
+						Name:    "armor",
+						Aliases: []string{"a"},
+						Usage:   "write ASCII armored output",
+					},
+					&cli.PathFlag{
+						Name:      "output",
+						Aliases:   []string{"o"},
+						Usage:     "file to write to instead of stdout",
+						TakesFile: true,
+					},
+				}, recipientFlags...),
+				Action: func(c *cli.Context) error {
+					recipients, err := encryptRecipients(c)
+					if err != nil {
+						return err
+					}
+					if !c.Bool("armor") && c.String("output") == "" && isTerminal(os.Stdout) {
+						return errors.New("refusing to write binary output to a terminal, use --armor")
+					}
+					return streamFile(c.Args().First(), c.String("output"), 0o666, func(w io.Writer, r io.Reader) error {
+						return crypt.Encrypt(w, r, recipients, c.Bool("armor"))
+					})
+				},
+			},
+			{
+				Name:      "decrypt",
+				Aliases:   []string{"d"},
+				Usage:     "decrypt a file or stdin",
+				ArgsUsage: "[file]",
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{
+						Name:      "identity",
+						Aliases:   []string{"i"},
+						Usage:     "identity file to decrypt with, instead of the default identities",
+						TakesFile: true,
+					},
+					&cli.PathFlag{
+						Name:      "output",
+						Aliases:   []string{"o"},
+						Usage:     "file to write to instead of stdout",
+						TakesFile: true,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					identities, err := cryptIdentities(c)
+					if err != nil {
+						return err
+					}
+					return streamFile(c.Args().First(), c.String("output"), 0o600, func(w io.Writer, r io.Reader) error {
+						return crypt.Decrypt(w, r, identities)
+					})
+				},
+			},
+			{
+				Name:      "edit",
+				Usage:     "edit an encrypted file with $EDITOR",
+				ArgsUsage: "file",
+				Description: "The file is decrypted into a temporary directory only accessible by the current user,\n" +
+					"preferably below $XDG_RUNTIME_DIR, and shredded once the editor exits. Without recipient flags\n" +
+					"the file is re-encrypted to the recipients in <file>.recipients or in the " + pass.RecipientsFile + "\n" +
+					"file of its directory. A file that does not exist yet is created.",
+				Flags: append([]cli.Flag{
+					&cli.StringSliceFlag{
+						Name:      "identity",
+						Aliases:   []string{"i"},
+						Usage:     "identity file to decrypt with, instead of the default identities",
+						TakesFile: true,
+					},
+					&cli.BoolFlag{
+						Name:    "armor",
+						Aliases: []string{"a"},
+						Usage:   "write ASCII armored output, by default the existing format is kept",
+					},
+				}, recipientFlags...),
+				Action: func(c *cli.Context) error {
+					if c.Args().Len() != 1 {
+						return errors.New("invalid number of arguments")
+					}
+					return editEncrypted(c, c.Args().First())
+				},
+			},
+			{
+				Name:      "exec",
+				Usage:     "run a command with variables from encrypted env files",
+				ArgsUsage: "-- command [args...]",
+				Description: "Env files are dotenv, JSON or YAML files encrypted with age, the format is guessed\n" +
+					"from the file name. They are decrypted in memory only. With --shell the arguments are\n" +
+					"joined and run as a script by the builtin POSIX shell interpreter.",
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{
+						Name:      "env",
+						Aliases:   []string{"e"},
+						Usage:     "encrypted env file, later files override earlier ones",
+						Required:  true,
+						TakesFile: true,
+					},
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "format of the env files: dotenv, json or yaml",
+					},
+					&cli.StringSliceFlag{
+						Name:    "key",
+						Aliases: []string{"k"},
+						Usage:   "only pass these variables",
+					},
+					&cli.StringSliceFlag{
+						Name:  "rename",
+						Usage: "pass variable OLD as NEW, given as OLD=NEW",
+					},
+					&cli.StringSliceFlag{
+						Name:      "identity",
+						Aliases:   []string{"i"},
+						Usage:     "identity file to decrypt with, instead of the default identities",
+						TakesFile: true,
+					},
+					&cli.BoolFlag{
+						Name:    "shell",
+						Aliases: []string{"s"},
+						Usage:   "run the arguments as shell script",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if c.Args().Len() == 0 {
+						return errors.New("no command given")
+					}
+					env, err := secretEnv(c)
+					if err != nil {
+						return err
+					}
+					if c.Bool("shell") {
+						return runScript(strings.Join(c.Args().Slice(), " "), env)
+					}
+					cmd := exec.Command(c.Args().First(), c.Args().Tail()...)
+					cmd.Env = env
+					cmd.Stdin = os.Stdin
+					cmd.Stdout = os.Stdout
+					cmd.Stderr = os.Stderr
+					err = cmd.Run()
+					var exitErr *exec.ExitError
+					if errors.As(err, &exitErr) {
+						return cli.Exit("", exitErr.ExitCode())
+					}
+					return err
+				},
+			},
+			{
+				Name:  "keygen",
+				Usage: "generate an X25519 identity",
+				Flags: []cli.Flag{
+					&cli.PathFlag{
+						Name:      "output",
+						Aliases:   []string{"o"},
+						Usage:     "file to write the identity to instead of stdout",
+						TakesFile: true,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					identity, recipient, err := crypt.GenerateIdentity()
+					if err != nil {
+						return err
+					}
+					output := c.String("output")
+					if output == "" {
+						if !isTerminal(os.Stdout) {
+							fmt.Fprintf(os.Stderr, "Public key: %s\n", recipient)
+						}
+						_, err = os.Stdout.Write(identity)
+						return err
+					}
+					file, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+					if err != nil {
+						return fmt.Errorf("failed to create identity file: %w", err)
+					}
+					_, err = file.Write(identity)
+					if closeErr := file.Close(); err == nil {
+						err = closeErr
+					}
+					if err != nil {
+						return fmt.Errorf("failed to write identity file: %w", err)
+					}
+					fmt.Fprintf(os.Stderr, "Public key: %s\n", recipient)
+					return nil
+				},
+			},
+			{
+				Name:      "recipients",
+				Usage:     "print the public keys of identity files",
+				ArgsUsage: "[identity-file...]",
+				Action: func(c *cli.Context) error {
+					files := c.Args().Slice()
+					if len(files) == 0 {
+						files = []string{"-"}
+					}
+					for _, file := range files {
+						var data []byte
+						var err error
+						if file == "-" {
+							data, err = io.ReadAll(os.Stdin)
+						} else {
+							data, err = os.ReadFile(file)
+						}
+						if err != nil {
+							return fmt.Errorf("failed to read identity file: %w", err)
+						}
+						recipients, err := crypt.Recipients(data)
+						if err != nil {
+							return fmt.Errorf("failed to parse identity file %s: %w", file, err)
+						}
+						for _, recipient := range recipients {
+							fmt.Println(recipient)
+						}
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
+
+var recipientFlags = []cli.Flag{
+	&cli.StringSliceFlag{
+		Name:    "recipient",
+		Aliases: []string{"r"},
+		Usage:   "age or ssh public key to encrypt to",
+	},
+	&cli.StringSliceFlag{
+		Name:      "recipients-file",
+		Aliases:   []string{"R"},
+		Usage:     "file with recipients to encrypt to, one per line",
+		TakesFile: true,
+	},
+	&cli.BoolFlag{
+		Name:    "passphrase",
+		Aliases: []string{"p"},
+		Usage:   "encrypt with a passphrase instead of recipients",
+	},
+}
+
+// encryptRecipients collects the recipients given on the command line, or a
+// scrypt recipient if a passphrase is requested.
+func encryptRecipients(c *cli.Context) ([]age.Recipient, error) {
+	var recipients []age.Recipient
+	for _, arg := range c.StringSlice("recipient") {
+		recipient, err := crypt.ParseRecipient(arg)
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, recipient)
+	}
+	for _, file := range c.StringSlice("recipients-file") {
+		fileRecipients, err := crypt.ReadRecipientsFile(file)
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, fileRecipients...)
+	}
+	if c.Bool("passphrase") {
+		// age only allows a scrypt recipient on its own.
+		if len(recipients) > 0 {
+			return nil, errors.New("a passphrase can not be combined with recipients")
+		}
+		passphrase, err := readNewSecret("Passphrase")
+		if err != nil {
+			return nil, err
+		}
+		if passphrase == "" {
+			return nil, errors.New("passphrase must not be empty")
+		}
+		recipient, err := age.NewScryptRecipient(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return []age.Recipient{recipient}, nil
+	}
+	if len(recipients) == 0 {
+		return nil, errors.New("no recipients given, use --recipient, --recipients-file or --passphrase")
+	}
+	return recipients, nil
+}
+
+// cryptIdentities loads the identity files given on the command line, or
+// the identities of the password store if none are given. Passphrase
+// encrypted files can always be decrypted.
+func cryptIdentities(c *cli.Context) ([]age.Identity, error) {
+	passphrase := func() (string, error) {
+		return readSecret("Enter passphrase: ")
+	}
+	files := c.StringSlice("identity")
+	if len(files) == 0 {
+		identities, err := loadIdentities(c)
+		if errors.Is(err, os.ErrNotExist) {
+			return []age.Identity{&pass.LazyScryptIdentity{Passphrase: passphrase}}, nil
+		}
+		return identities, err
+	}
+	var identities []age.Identity
+	for _, file := range files {
+		fileIdentities, err := crypt.ReadIdentityFile(file, func() (string, error) {
+			return readSecret("Enter passphrase for " + file + ": ")
+		})
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, fileIdentities...)
+	}
+	return append(identities, &pass.LazyScryptIdentity{Passphrase: passphrase}), nil
+}
+
+// editEncrypted decrypts path, opens it in the editor and re-encrypts it if
+// it was changed.
+func editEncrypted(c *cli.Context, path string) error {
+	var recipients []age.Recipient
+	var err error
+	if c.IsSet("recipient") || c.IsSet("recipients-file") || c.Bool("passphrase") {
+		recipients, err = encryptRecipients(c)
+	} else {
+		recipients, err = sidecarRecipients(path)
+	}
 	if err != nil {
-		return "", err
+		return err
 	}
-	defer file.Close()
-	idReader := strings.NewReader("AGE-PLUGIN-YUBIKEY-1JAAZ6QVZ0RQLA0GD5ZEPL\n" +
-		"AGE-PLUGIN-YUBIKEY-1ZJRRUQVZE9DJFCC3UPNJD\n" +
-		"AGE-PLUGIN-YUBIKEY-1ZWRRUQVZAJX2Q4C5LJRTD")
-	identities, err := age.ParseIdentities(idReader)
+	perm := os.FileMode(0o600)
+	armored := c.Bool("armor")
+	var data []byte
+	encrypted, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		stat, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		perm = stat.Mode().Perm()
+		armored = armored || crypt.IsArmored(encrypted)
+		identities, err := cryptIdentities(c)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		err = crypt.Decrypt(&buf, bytes.NewReader(encrypted), identities)
+		if err != nil {
+			return err
+		}
+		data = buf.Bytes()
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+	edited, changed, err := edit.Content(strings.TrimSuffix(filepath.Base(path), ".age"), data)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		logger.Info("file unchanged", "path", path)
+		return nil
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	err = crypt.Encrypt(tmp, bytes.NewReader(edited), recipients, armored)
+	if err == nil {
+		err = tmp.Chmod(perm)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// secretEnv decrypts the env files of the exec command and returns the
+// current environment with their selected variables added.
+func secretEnv(c *cli.Context) ([]string, error) {
+	identities, err := cryptIdentities(c)
+	if err != nil {
+		return nil, err
+	}
+	secrets := map[string]string{}
+	for _, path := range c.StringSlice("env") {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open env file: %w", err)
+		}
+		var buf bytes.Buffer
+		err = crypt.Decrypt(&buf, file, identities)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt %s: %w", path, err)
+		}
+		format := c.String("format")
+		if format == "" {
+			format = crypt.EnvFormat(path)
+		}
+		values, err := crypt.ParseEnv(buf.Bytes(), format)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		for key, value := range values {
+			secrets[key] = value
+		}
+	}
+	if c.IsSet("key") {
+		selected := map[string]string{}
+		for _, key := range c.StringSlice("key") {
+			value, ok := secrets[key]
+			if !ok {
+				return nil, fmt.Errorf("variable %s not found in env files", key)
+			}
+			selected[key] = value
+		}
+		secrets = selected
+	}
+	for _, rename := range c.StringSlice("rename") {
+		from, to, ok := strings.Cut(rename, "=")
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid rename %q, expected OLD=NEW", rename)
+		}
+		value, ok := secrets[from]
+		if !ok {
+			return nil, fmt.Errorf("variable %s not found in env files", from)
+		}
+		delete(secrets, from)
+		secrets[to] = value
+	}
+	env := os.Environ()
+	for key, value := range secrets {
+		env = append(env, key+"="+value)
+	}
+	return env, nil
+}
+
+// runScript runs script with the builtin shell interpreter, exiting with
+// its exit status.
+func runScript(script string, env []string) error {
+	file, err := syntax.NewParser().Parse(strings.NewReader(script), "")
+	if err != nil {
+		return fmt.Errorf("failed to parse script: %w", err)
+	}
+	runner, err := interp.New(
+		interp.Env(expand.ListEnviron(env...)),
+		interp.StdIO(os.Stdin, os.Stdout, os.Stderr),
+	)
+	if err != nil {
+		return err
+	}
+	err = runner.Run(context.Background(), file)
+	if status, ok := interp.IsExitStatus(err); ok {
+		return cli.Exit("", int(status))
+	}
+	return err
+}
+
+// sidecarRecipients reads the recipients of the encrypted file at path from
+// path.recipients, or the recipients file of its directory.
+func sidecarRecipients(path string) ([]age.Recipient, error) {
+	for _, file := range []string{path + ".recipients", filepath.Join(filepath.Dir(path), pass.RecipientsFile)} {
+		_, err := os.Stat(file)
+		if err == nil {
+			return crypt.ReadRecipientsFile(file)
+		}
+	}
+	return nil, fmt.Errorf("no recipients given and no %s.recipients or %s found", path, pass.RecipientsFile)
+}
+
+// streamFile runs fn with input read from the file in, or stdin, and output
+// written to the file out, or stdout. A partially written output file is
+// removed if fn fails.
+func streamFile(in, out string, perm os.FileMode, fn func(io.Writer, io.Reader) error) error {
+	var r io.Reader = os.Stdin
+	if in != "" && in != "-" {
+		file, err := os.Open(in)
+		if err != nil {
+			return fmt.Errorf("failed to open input: %w", err)
+		}
+		defer file.Close()
+		r = file
+	}
+	if out == "" || out == "-" {
+		return fn(os.Stdout, r)
+	}
+	file, err := os.OpenFile(out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
 	if err != nil {
-		return "", err
+		return fmt.Errorf("failed to create output: %w", err)
+	}
+	err = fn(file, r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
 	}
-	decrypt, err := age.Decrypt(file, identities...)
 	if err != nil {
-		return "", err
+		_ = os.Remove(out)
 	}
-	all, err := io.ReadAll(decrypt)
+	return err
+}
+
+// decryptFile streams the decrypted content of the file at filePath to w.
+func decryptFile(filePath string, w io.Writer, identities []age.Identity) error {
+	file, err := os.Open(filePath)
 	if err != nil {
-		return "", err
+		return err
 	}
-	return string(all), nil
+	defer file.Close()
+	return crypt.Decrypt(w, file, identities)
 }
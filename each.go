@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"os"
+	"os/signal"
+	"runtime"
+	"tasadar.net/tionis/shell-tools/each"
+)
+
+func eachCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "each",
+		Usage: "run a command for every item read from stdin, like xargs",
+		Description: "Items are lines, NUL separated strings or JSONL records. In the command {} is replaced with the\n" +
+			"item, {#} with its index and {.field} with a field of a JSONL record, nested fields being\n" +
+			"separated by dots. Without placeholders the item is appended as last argument.\n" +
+			"With --shell the command is run by the embedded shell interpreter, with values shell quoted.",
+		ArgsUsage: "command [args...]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "split",
+				Usage: "how to split stdin into items: line, null or jsonl",
+				Value: each.SplitLine,
+			},
+			&cli.BoolFlag{
+				Name:    "null",
+				Aliases: []string{"0"},
+				Usage:   "items are separated by NUL, like --split null",
+			},
+			&cli.IntFlag{
+				Name:    "jobs",
+				Aliases: []string{"j"},
+				Usage:   "number of jobs to run in parallel",
+				Value:   runtime.NumCPU(),
+			},
+			&cli.BoolFlag{
+				Name:    "keep-order",
+				Aliases: []string{"k"},
+				Usage:   "buffer the output of jobs and write it in input order",
+			},
+			&cli.StringFlag{
+				Name:      "joblog",
+				Usage:     "append a JSON line per finished job to this file",
+				TakesFile: true,
+			},
+			&cli.IntFlag{
+				Name:  "retries",
+				Usage: "run failed jobs up to this many more times",
+			},
+			&cli.BoolFlag{
+				Name:  "halt-on-error",
+				Usage: "start no new jobs once a job failed",
+			},
+			&cli.BoolFlag{
+				Name:    "shell",
+				Aliases: []string{"s"},
+				Usage:   "run the command with the embedded shell interpreter",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() == 0 {
+				return errors.New("no command given")
+			}
+			opts := each.Options{
+				Split:       c.String("split"),
+				Jobs:        c.Int("jobs"),
+				KeepOrder:   c.Bool("keep-order"),
+				Retries:     c.Int("retries"),
+				HaltOnError: c.Bool("halt-on-error"),
+				Shell:       c.Bool("shell"),
+				Stdout:      os.Stdout,
+				Stderr:      os.Stderr,
+			}
+			if c.Bool("null") {
+				opts.Split = each.SplitNull
+			}
+			if c.String("joblog") != "" {
+				joblog, err := os.OpenFile(c.String("joblog"), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+				if err != nil {
+					return fmt.Errorf("failed to open joblog: %w", err)
+				}
+				defer joblog.Close()
+				opts.Joblog = joblog
+			}
+			ctx, stop := signal.NotifyContext(c.Context, os.Interrupt)
+			defer stop()
+			return each.Run(ctx, c.Args().Slice(), os.Stdin, opts)
+		},
+	}
+}
@@ -0,0 +1,312 @@
+// Package each implements the each command, which runs a command template
+// for every item of its input, in parallel.
+package each
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mvdan.cc/sh/v3/interp"
+	"mvdan.cc/sh/v3/syntax"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"tasadar.net/tionis/shell-tools/moreutils"
+	"time"
+)
+
+// errHalted stops reading items once a job failed with HaltOnError.
+var errHalted = errors.New("halted")
+
+// Options configure [Run].
+type Options struct {
+	// Split is how the input is split into items, SplitLine by default.
+	Split string
+	// Jobs is the number of jobs run in parallel, at least one.
+	Jobs int
+	// KeepOrder buffers the output of every job and writes it in input
+	// order. Otherwise jobs write their output as it is produced.
+	KeepOrder bool
+	// Retries is how often a failed job is run again.
+	Retries int
+	// HaltOnError stops starting new jobs once a job failed, running jobs
+	// are still waited for.
+	HaltOnError bool
+	// Shell runs the template as script of the embedded shell interpreter,
+	// with shell quoted values, instead of running it as command and args.
+	Shell bool
+	// Joblog receives a JSON line per finished job, if set.
+	Joblog io.Writer
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// LogEntry is a line of the joblog.
+type LogEntry struct {
+	Seq      int       `json:"seq"`
+	Item     string    `json:"item"`
+	Command  string    `json:"command"`
+	Start    time.Time `json:"start"`
+	Duration float64   `json:"duration"`
+	Exit     int       `json:"exit"`
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// result is a finished job along with its buffered output.
+type result struct {
+	LogEntry
+	err            error
+	stdout, stderr *moreutils.Buffer
+}
+
+// syncWriter serializes writes of concurrent jobs.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+// Run runs template for every item read from input. In exec mode the
+// template is a command and its args, each expanded separately, and the item
+// is appended as last arg if no placeholder is used. In shell mode the
+// template is joined to a script. It returns an error if any job failed.
+func Run(ctx context.Context, template []string, input io.Reader, opts Options) error {
+	if len(template) == 0 {
+		return errors.New("no command given")
+	}
+	if opts.Split == "" {
+		opts.Split = SplitLine
+	}
+	if opts.Jobs < 1 {
+		opts.Jobs = 1
+	}
+	if opts.Stdout == nil {
+		opts.Stdout = io.Discard
+	}
+	if opts.Stderr == nil {
+		opts.Stderr = io.Discard
+	}
+	if !opts.KeepOrder {
+		opts.Stdout = &syncWriter{w: opts.Stdout}
+		opts.Stderr = &syncWriter{w: opts.Stderr}
+	}
+	if !opts.Shell && !hasPlaceholders(template) {
+		template = append(template[:len(template):len(template)], "{}")
+	}
+
+	items := make(chan Item)
+	results := make(chan result)
+	halt := make(chan struct{})
+	var readErr error
+	go func() {
+		defer close(items)
+		readErr = ReadItems(input, opts.Split, func(item Item) error {
+			select {
+			case items <- item:
+				return nil
+			case <-halt:
+				return errHalted
+			}
+		})
+	}()
+	var haltOnce sync.Once
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range items {
+				select {
+				case <-halt:
+					// Items taken just before the halt are skipped.
+					continue
+				default:
+				}
+				res := runJob(ctx, template, item, opts)
+				if res.err != nil && opts.HaltOnError {
+					haltOnce.Do(func() { close(halt) })
+				}
+				results <- res
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var errs []error
+	total, failed := 0, 0
+	pending := map[int]result{}
+	next := 1
+	for res := range results {
+		total++
+		if res.err != nil {
+			failed++
+		}
+		if opts.Joblog != nil {
+			err := json.NewEncoder(opts.Joblog).Encode(res.LogEntry)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to write joblog: %w", err))
+			}
+		}
+		if !opts.KeepOrder {
+			continue
+		}
+		pending[res.Seq] = res
+		for res, ok := pending[next]; ok; res, ok = pending[next] {
+			errs = append(errs, writeOutput(res, opts))
+			delete(pending, next)
+			next++
+		}
+	}
+	// After a halt, skipped items leave gaps in the order.
+	seqs := make([]int, 0, len(pending))
+	for seq := range pending {
+		seqs = append(seqs, seq)
+	}
+	sort.Ints(seqs)
+	for _, seq := range seqs {
+		errs = append(errs, writeOutput(pending[seq], opts))
+	}
+	if readErr != nil && !errors.Is(readErr, errHalted) {
+		errs = append(errs, readErr)
+	}
+	if failed > 0 {
+		message := fmt.Sprintf("%d of %d jobs failed", failed, total)
+		if opts.HaltOnError {
+			message += ", halted"
+		}
+		errs = append(errs, errors.New(message))
+	}
+	return errors.Join(errs...)
+}
+
+func hasPlaceholders(template []string) bool {
+	for _, arg := range template {
+		if HasPlaceholders(arg) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeOutput(res result, opts Options) error {
+	defer res.stdout.Close()
+	defer res.stderr.Close()
+	_, err := res.stdout.WriteTo(opts.Stdout)
+	if err == nil {
+		_, err = res.stderr.WriteTo(opts.Stderr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// runJob runs the template for item, retrying as configured.
+func runJob(ctx context.Context, template []string, item Item, opts Options) result {
+	res := result{LogEntry: LogEntry{Seq: item.Index, Item: item.Text, Start: time.Now()}}
+	var args []string
+	var script string
+	var err error
+	if opts.Shell {
+		script, err = Expand(strings.Join(template, " "), item, shellQuote)
+		res.Command = script
+	} else {
+		args = make([]string, len(template))
+		for i, arg := range template {
+			var argErr error
+			args[i], argErr = Expand(arg, item, nil)
+			err = errors.Join(err, argErr)
+		}
+		quoted := make([]string, len(args))
+		for i, arg := range args {
+			quoted[i] = shellQuote(arg)
+		}
+		res.Command = strings.Join(quoted, " ")
+	}
+	if err != nil {
+		res.err, res.Exit, res.Error = err, -1, err.Error()
+		res.stdout = moreutils.NewBuffer(0)
+		res.stderr = moreutils.NewBuffer(0)
+		return res
+	}
+	for attempt := 0; attempt <= opts.Retries; attempt++ {
+		res.Attempts++
+		stdout, stderr := opts.Stdout, opts.Stderr
+		if opts.KeepOrder {
+			// Only the output of the last attempt is kept.
+			if res.stdout != nil {
+				res.stdout.Close()
+				res.stderr.Close()
+			}
+			res.stdout = moreutils.NewBuffer(moreutils.DefaultBufferLimit)
+			res.stderr = moreutils.NewBuffer(moreutils.DefaultBufferLimit)
+			stdout, stderr = res.stdout, res.stderr
+		}
+		if opts.Shell {
+			err = runScript(ctx, script, stdout, stderr)
+		} else {
+			cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+			cmd.Stdout = stdout
+			cmd.Stderr = stderr
+			err = cmd.Run()
+		}
+		res.err, res.Exit = err, exitCode(err)
+		if err == nil || ctx.Err() != nil {
+			break
+		}
+	}
+	if res.err != nil {
+		res.Error = res.err.Error()
+	}
+	res.Duration = time.Since(res.Start).Seconds()
+	return res
+}
+
+func runScript(ctx context.Context, script string, stdout, stderr io.Writer) error {
+	file, err := syntax.NewParser().Parse(strings.NewReader(script), "each")
+	if err != nil {
+		return fmt.Errorf("failed to parse command: %w", err)
+	}
+	runner, err := interp.New(interp.StdIO(nil, stdout, stderr))
+	if err != nil {
+		return fmt.Errorf("failed to set up interpreter: %w", err)
+	}
+	return runner.Run(ctx, file)
+}
+
+// exitCode returns the exit code of a command that returned err, or -1 if
+// it did not exit on its own.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if status, ok := interp.IsExitStatus(err); ok {
+		return int(status)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+func shellQuote(s string) string {
+	quoted, err := syntax.Quote(s, syntax.LangBash)
+	if err != nil {
+		return strconv.Quote(s)
+	}
+	return quoted
+}
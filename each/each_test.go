@@ -0,0 +1,74 @@
+package each
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpand(t *testing.T) {
+	var items []Item
+	input := `{"name": "a b", "size": 3, "tags": ["x"], "meta": {"owner": "me"}}` + "\n\n" + `{"name": "c"}`
+	require.NoError(t, ReadItems(strings.NewReader(input), SplitJSONL, func(item Item) error {
+		items = append(items, item)
+		return nil
+	}))
+	require.Len(t, items, 2)
+	expanded, err := Expand("{#}: {.name} {.size} {.tags} {.meta.owner} {x}", items[0], shellQuote)
+	require.NoError(t, err)
+	assert.Equal(t, `1: 'a b' 3 '["x"]' me {x}`, expanded)
+	_, err = Expand("{.size}", items[1], nil)
+	assert.ErrorContains(t, err, "item 2 has no field size")
+
+	items = nil
+	require.NoError(t, ReadItems(strings.NewReader("a\nb c\x00d"), SplitNull, func(item Item) error {
+		items = append(items, item)
+		return nil
+	}))
+	assert.Equal(t, []Item{{Index: 1, Text: "a\nb c"}, {Index: 2, Text: "d"}}, items)
+}
+
+func TestRunKeepOrder(t *testing.T) {
+	var stdout, joblog bytes.Buffer
+	input := "3\n1\n2\n"
+	err := Run(context.Background(), []string{"sh", "-c", "sleep 0.$1; echo $1", "-"}, strings.NewReader(input), Options{
+		Jobs:      3,
+		KeepOrder: true,
+		Stdout:    &stdout,
+		Joblog:    &joblog,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "3\n1\n2\n", stdout.String())
+
+	var entries []LogEntry
+	decoder := json.NewDecoder(&joblog)
+	for decoder.More() {
+		var entry LogEntry
+		require.NoError(t, decoder.Decode(&entry))
+		entries = append(entries, entry)
+	}
+	require.Len(t, entries, 3)
+	assert.Equal(t, 2, entries[0].Seq, "the joblog is in order of completion")
+	assert.Equal(t, "sh -c 'sleep 0.$1; echo $1' - 1", entries[0].Command)
+}
+
+func TestRunShellRetriesHalt(t *testing.T) {
+	dir := t.TempDir()
+	var stdout bytes.Buffer
+	// Fails on the first attempt for every item, and on every attempt for b.
+	script := []string{"if [ {} = b ] || ! [ -e " + dir + "/{#} ]; then touch " + dir + "/{#}; echo fail {}; exit 3; fi; echo ok {}"}
+	err := Run(context.Background(), script, strings.NewReader("a\nb\nc\n"), Options{
+		Shell:       true,
+		Retries:     1,
+		HaltOnError: true,
+		KeepOrder:   true,
+		Stdout:      &stdout,
+	})
+	assert.EqualError(t, err, "1 of 2 jobs failed, halted")
+	assert.Equal(t, "ok a\nfail b\n", stdout.String())
+}
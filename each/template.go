@@ -0,0 +1,146 @@
+package each
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Ways of splitting the input into items.
+const (
+	SplitLine  = "line"
+	SplitNull  = "null"
+	SplitJSONL = "jsonl"
+)
+
+// Item is a single input of a job.
+type Item struct {
+	// Index is the position of the item in the input, starting at 1.
+	Index int
+	// Text is the item as read, without its separator.
+	Text string
+	// Record is the decoded object of a JSONL item.
+	Record map[string]any
+}
+
+// ReadItems splits r into items and calls fn with each of them, stopping at
+// the first error of fn. Empty lines and records are skipped.
+func ReadItems(r io.Reader, split string, fn func(Item) error) error {
+	separator := byte('\n')
+	switch split {
+	case SplitLine, SplitJSONL:
+	case SplitNull:
+		separator = 0
+	default:
+		return fmt.Errorf("unknown split %q, expected line, null or jsonl", split)
+	}
+	reader := bufio.NewReader(r)
+	index := 0
+	for {
+		text, readErr := reader.ReadString(separator)
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return fmt.Errorf("failed to read input: %w", readErr)
+		}
+		text = strings.TrimSuffix(text, string(separator))
+		if split != SplitNull {
+			text = strings.TrimSuffix(text, "\r")
+		}
+		if strings.TrimSpace(text) != "" {
+			index++
+			item := Item{Index: index, Text: text}
+			if split == SplitJSONL {
+				decoder := json.NewDecoder(strings.NewReader(text))
+				decoder.UseNumber()
+				err := decoder.Decode(&item.Record)
+				if err != nil {
+					return fmt.Errorf("failed to decode record %d: %w", index, err)
+				}
+			}
+			err := fn(item)
+			if err != nil {
+				return err
+			}
+		}
+		if readErr != nil {
+			return nil
+		}
+	}
+}
+
+// placeholderPattern matches {}, {#} and {.field.path}.
+var placeholderPattern = regexp.MustCompile(`\{(#|\.[^{}\s]+)?\}`)
+
+// HasPlaceholders reports whether s contains a placeholder.
+func HasPlaceholders(s string) bool {
+	return placeholderPattern.MatchString(s)
+}
+
+// Expand replaces the placeholders in s with the values of item, passed
+// through quote if it is not nil: {} with the item, {#} with its index and
+// {.field} with a field of its record, nested fields being separated by
+// dots. Strings are inserted as is, other values as JSON.
+func Expand(s string, item Item, quote func(string) string) (string, error) {
+	var err error
+	expanded := placeholderPattern.ReplaceAllStringFunc(s, func(placeholder string) string {
+		name := placeholder[1 : len(placeholder)-1]
+		var value string
+		switch {
+		case name == "":
+			value = item.Text
+		case name == "#":
+			value = strconv.Itoa(item.Index)
+		default:
+			var fieldErr error
+			value, fieldErr = item.field(strings.TrimPrefix(name, "."))
+			if fieldErr != nil {
+				err = errors.Join(err, fieldErr)
+			}
+		}
+		if quote != nil {
+			return quote(value)
+		}
+		return value
+	})
+	return expanded, err
+}
+
+// field returns the value at the dot separated path in the record.
+func (i Item) field(path string) (string, error) {
+	if i.Record == nil {
+		return "", fmt.Errorf("item %d is not a record, field %s needs --split jsonl", i.Index, path)
+	}
+	var value any = i.Record
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return "", fmt.Errorf("item %d: %s is not an object", i.Index, path)
+		}
+		value, ok = object[key]
+		if !ok {
+			return "", fmt.Errorf("item %d has no field %s", i.Index, path)
+		}
+	}
+	switch value := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	case json.Number:
+		return value.String(), nil
+	default:
+		var b bytes.Buffer
+		encoder := json.NewEncoder(&b)
+		encoder.SetEscapeHTML(false)
+		err := encoder.Encode(value)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSuffix(b.String(), "\n"), nil
+	}
+}
@@ -0,0 +1,154 @@
+// Package edit opens files and content in the editor of the user, keeping
+// temporary copies private.
+package edit
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// SecureTempDir creates a directory only accessible by the current user,
+// preferably below $XDG_RUNTIME_DIR, which is usually kept in memory.
+// It refuses to use a parent directory that other users can write to without
+// the sticky bit set, or a runtime dir that is readable by others.
+func SecureTempDir() (string, error) {
+	parent := os.Getenv("XDG_RUNTIME_DIR")
+	if parent != "" {
+		stat, err := os.Stat(parent)
+		if err != nil {
+			return "", fmt.Errorf("failed to stat XDG_RUNTIME_DIR: %w", err)
+		}
+		if stat.Mode().Perm()&0o077 != 0 {
+			return "", fmt.Errorf("XDG_RUNTIME_DIR %s is accessible by other users", parent)
+		}
+	} else {
+		parent = os.TempDir()
+		stat, err := os.Stat(parent)
+		if err != nil {
+			return "", fmt.Errorf("failed to stat temp dir: %w", err)
+		}
+		if stat.Mode().Perm()&0o002 != 0 && stat.Mode()&os.ModeSticky == 0 {
+			return "", fmt.Errorf("temp dir %s is world-writable without sticky bit", parent)
+		}
+	}
+	dir, err := os.MkdirTemp(parent, "shell-tools-")
+	if err != nil {
+		return "", err
+	}
+	err = os.Chmod(dir, 0o700)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+// Shred overwrites the file at path with zeros and removes it.
+func Shred(path string) error {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err == nil {
+		_, err = file.Write(make([]byte, stat.Size()))
+		if err == nil {
+			err = file.Sync()
+		}
+		_ = file.Close()
+	}
+	return errors.Join(err, os.Remove(path))
+}
+
+// Editor returns the command line of the editor configured by $VISUAL or
+// $EDITOR, falling back to vi.
+func Editor() []string {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		return []string{"vi"}
+	}
+	return strings.Fields(editor)
+}
+
+// ErrInterrupted is returned if editing was interrupted by a signal.
+var ErrInterrupted = errors.New("interrupted")
+
+// File opens path in the configured editor and waits for it to exit.
+// Interrupts do not terminate the current process while the editor runs, so
+// callers get to clean up the edited file. SIGINT is left to the editor,
+// which shares the terminal and may well use Ctrl-C itself, while SIGTERM
+// and SIGHUP are forwarded to it and abort the edit.
+func File(path string) error {
+	editor := Editor()
+	cmd := exec.Command(editor[0], append(editor[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+	err := cmd.Start()
+	if err != nil {
+		return fmt.Errorf("failed to start editor: %w", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	interrupted := false
+	for {
+		select {
+		case sig := <-signals:
+			if sig != os.Interrupt {
+				interrupted = true
+				_ = cmd.Process.Signal(sig)
+			}
+		case err = <-done:
+			if interrupted {
+				return ErrInterrupted
+			}
+			if err != nil {
+				return fmt.Errorf("editor failed: %w", err)
+			}
+			return nil
+		}
+	}
+}
+
+// Content writes data to a file in a secure temporary directory, opens it in
+// the configured editor and returns the edited content. The temporary file
+// is shredded afterwards. changed reports whether the content differs.
+func Content(name string, data []byte) (edited []byte, changed bool, err error) {
+	dir, err := SecureTempDir()
+	if err != nil {
+		return nil, false, err
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, filepath.Base(filepath.FromSlash(name))+".txt")
+	err = os.WriteFile(path, data, 0o600)
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() {
+		err = errors.Join(err, Shred(path))
+	}()
+	err = File(path)
+	if err != nil {
+		return nil, false, err
+	}
+	edited, err = os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	return edited, !bytes.Equal(data, edited), nil
+}
@@ -0,0 +1,174 @@
+package entr
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Restart policies deciding what happens when a task is triggered while it is
+// still running.
+const (
+	// RestartQueue runs the task again once the current run has finished.
+	RestartQueue = "queue"
+	// RestartKill cancels the current run and starts a new one.
+	RestartKill = "restart"
+	// RestartIgnore drops triggers arriving while the task is running.
+	RestartIgnore = "ignore"
+)
+
+// Config describes a set of named tasks watched by a single entr process.
+type Config struct {
+	Tasks map[string]*Task `yaml:"tasks"`
+	// Dir is the directory relative paths and commands are resolved in.
+	// It defaults to the directory containing the config file.
+	Dir string `yaml:"dir"`
+}
+
+// Task is a command run whenever its watched files change or a task it
+// depends on finishes successfully.
+type Task struct {
+	Paths     []string      `yaml:"paths"`
+	Recursive []string      `yaml:"recursive"`
+	Globs     []string      `yaml:"globs"`
+	Debounce  time.Duration `yaml:"debounce"`
+	Command   string        `yaml:"command"`
+	Restart   string        `yaml:"restart"`
+	After     []string      `yaml:"after"`
+}
+
+// LoadConfig reads and validates a YAML task config from path.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	var config Config
+	err = yaml.Unmarshal(data, &config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if config.Dir == "" {
+		config.Dir = filepath.Dir(path)
+	} else if !filepath.IsAbs(config.Dir) {
+		config.Dir = filepath.Join(filepath.Dir(path), config.Dir)
+	}
+	err = config.validate()
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (c *Config) validate() error {
+	if len(c.Tasks) == 0 {
+		return errors.New("config declares no tasks")
+	}
+	for name, task := range c.Tasks {
+		if task == nil {
+			return fmt.Errorf("task %s is empty", name)
+		}
+		if task.Command == "" {
+			return fmt.Errorf("task %s has no command", name)
+		}
+		switch task.Restart {
+		case "":
+			task.Restart = RestartQueue
+		case RestartQueue, RestartKill, RestartIgnore:
+		default:
+			return fmt.Errorf("task %s has invalid restart policy: %s", name, task.Restart)
+		}
+		for _, glob := range task.Globs {
+			_, err := filepath.Match(glob, "")
+			if err != nil {
+				return fmt.Errorf("task %s has invalid glob %s: %w", name, glob, err)
+			}
+		}
+		for _, dep := range task.After {
+			if _, ok := c.Tasks[dep]; !ok {
+				return fmt.Errorf("task %s depends on unknown task %s", name, dep)
+			}
+		}
+	}
+	return c.checkCycles()
+}
+
+func (c *Config) checkCycles() error {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("task %s depends on itself", name)
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, dep := range c.Tasks[name].After {
+			err := visit(dep)
+			if err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+	for _, name := range c.names() {
+		err := visit(name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Config) names() []string {
+	names := make([]string, 0, len(c.Tasks))
+	for name := range c.Tasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// matches reports whether path is relevant to the task. Tasks without globs
+// match every path, otherwise either the base name or the path relative to
+// base has to match one of the globs.
+func (t *Task) matches(base, path string) bool {
+	if len(t.Globs) == 0 {
+		return true
+	}
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		rel = path
+	}
+	for _, glob := range t.Globs {
+		if ok, _ := filepath.Match(glob, filepath.Base(path)); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(glob, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Task) resolve(base string, paths []string) []string {
+	resolved := make([]string, len(paths))
+	for i, path := range paths {
+		if filepath.IsAbs(path) {
+			resolved[i] = path
+		} else {
+			resolved[i] = filepath.Join(base, path)
+		}
+	}
+	return resolved
+}
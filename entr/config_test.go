@@ -0,0 +1,104 @@
+package entr
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, config string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "watch.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(config), 0o600))
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	config, err := LoadConfig(writeConfig(t, `
+tasks:
+  codegen:
+    recursive: [api]
+    globs: ["*.proto"]
+    debounce: 200ms
+    command: make codegen
+  build:
+    command: go build ./...
+    restart: restart
+    after: [codegen]
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"build", "codegen"}, config.names())
+	assert.Equal(t, 200*time.Millisecond, config.Tasks["codegen"].Debounce)
+	assert.Equal(t, RestartQueue, config.Tasks["codegen"].Restart)
+	assert.Equal(t, RestartKill, config.Tasks["build"].Restart)
+
+	codegen := config.Tasks["codegen"]
+	assert.True(t, codegen.matches(config.Dir, filepath.Join(config.Dir, "api", "v1", "user.proto")))
+	assert.False(t, codegen.matches(config.Dir, filepath.Join(config.Dir, "api", "v1", "user.go")))
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	for name, config := range map[string]string{
+		"no tasks":     `tasks: {}`,
+		"no command":   "tasks:\n  a:\n    paths: [.]\n",
+		"bad restart":  "tasks:\n  a:\n    command: true\n    restart: sometimes\n",
+		"unknown dep":  "tasks:\n  a:\n    command: true\n    after: [b]\n",
+		"cyclic deps":  "tasks:\n  a:\n    command: true\n    after: [b]\n  b:\n    command: true\n    after: [a]\n",
+		"invalid glob": "tasks:\n  a:\n    command: true\n    globs: [\"[\"]\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadConfig(writeConfig(t, config))
+			assert.Error(t, err)
+		})
+	}
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(data []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(data)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestRunnerDependencies(t *testing.T) {
+	config, err := LoadConfig(writeConfig(t, `
+tasks:
+  codegen:
+    command: echo generated
+  build:
+    command: echo built
+    after: [codegen]
+`))
+	require.NoError(t, err)
+	out := &syncBuffer{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runner := NewRunner(config, WatchOptions{}, out, io.Discard, false, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	go func() {
+		_ = runner.Run(ctx)
+	}()
+	require.Eventually(t, func() bool {
+		return strings.Contains(out.String(), "build   | built\n")
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "codegen | generated\nbuild   | built\n", out.String())
+}
@@ -0,0 +1,176 @@
+// Package entr implements the file watching behind the entr command.
+package entr
+
+import (
+	"fmt"
+	"github.com/rjeczalik/notify"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Operations reported in [Event.Op].
+const (
+	OpCreate = "create"
+	OpRemove = "remove"
+	OpWrite  = "write"
+	OpRename = "rename"
+)
+
+// Event is a single filesystem change reported by a watcher.
+type Event struct {
+	Path  string
+	Op    string
+	Time  time.Time
+	IsDir bool
+}
+
+// Watcher delivers filesystem events for a set of watched paths.
+type Watcher interface {
+	// Events returns the channel events are delivered on.
+	// It is closed once the watcher is closed.
+	Events() <-chan Event
+	// Close stops watching all paths.
+	Close() error
+}
+
+// WatchOptions selects and configures the watcher backend.
+type WatchOptions struct {
+	// Poll forces the polling backend even where native notifications work.
+	Poll bool
+	// PollInterval is the time between two scans of the polling backend.
+	PollInterval time.Duration
+	// PollHash makes the polling backend compare file contents by hash
+	// instead of relying on modification time and size alone.
+	PollHash bool
+}
+
+// Watch starts watching paths non-recursively and recursive paths with all
+// of their subdirectories. It falls back to polling if any of the paths lies
+// on a filesystem known not to deliver change notifications.
+func Watch(paths, recursive []string, opts WatchOptions) (Watcher, error) {
+	for _, list := range [][]string{paths, recursive} {
+		for _, path := range list {
+			if !supportsNotify(path) {
+				opts.Poll = true
+			}
+		}
+	}
+	if opts.Poll {
+		return NewPollWatcher(paths, recursive, opts.PollInterval, opts.PollHash)
+	}
+	return NewNotifyWatcher(paths, recursive)
+}
+
+type notifyWatcher struct {
+	raw       chan notify.EventInfo
+	events    chan Event
+	closeOnce sync.Once
+}
+
+// NewNotifyWatcher returns a watcher using the native change notifications
+// of the operating system.
+func NewNotifyWatcher(paths, recursive []string) (Watcher, error) {
+	w := &notifyWatcher{
+		raw:    make(chan notify.EventInfo, 10),
+		events: make(chan Event, 10),
+	}
+	for _, path := range paths {
+		err := notify.Watch(path, w.raw, notify.All)
+		if err != nil {
+			notify.Stop(w.raw)
+			return nil, fmt.Errorf("failed to watch %s: %w", path, err)
+		}
+	}
+	for _, path := range recursive {
+		err := notify.Watch(filepath.Join(path, "..."), w.raw, notify.All)
+		if err != nil {
+			notify.Stop(w.raw)
+			return nil, fmt.Errorf("failed to watch %s recursively: %w", path, err)
+		}
+	}
+	go w.forward()
+	return w, nil
+}
+
+func (w *notifyWatcher) forward() {
+	defer close(w.events)
+	for info := range w.raw {
+		event := Event{
+			Path: info.Path(),
+			Op:   opName(info.Event()),
+			Time: time.Now(),
+		}
+		if stat, err := os.Lstat(event.Path); err == nil {
+			event.IsDir = stat.IsDir()
+		}
+		w.events <- event
+	}
+}
+
+func (w *notifyWatcher) Events() <-chan Event {
+	return w.events
+}
+
+func (w *notifyWatcher) Close() error {
+	w.closeOnce.Do(func() {
+		notify.Stop(w.raw)
+		close(w.raw)
+	})
+	return nil
+}
+
+func opName(event notify.Event) string {
+	switch event {
+	case notify.Create:
+		return OpCreate
+	case notify.Remove:
+		return OpRemove
+	case notify.Write:
+		return OpWrite
+	case notify.Rename:
+		return OpRename
+	default:
+		return event.String()
+	}
+}
+
+// Batch groups events arriving less than window apart into a single batch.
+// With a window of zero every event is delivered as its own batch.
+// The returned channel is closed after events is closed and drained.
+func Batch(events <-chan Event, window time.Duration) <-chan []Event {
+	batches := make(chan []Event)
+	go func() {
+		defer close(batches)
+		for {
+			event, ok := <-events
+			if !ok {
+				return
+			}
+			batch := []Event{event}
+			if window > 0 {
+				timer := time.NewTimer(window)
+			collect:
+				for {
+					select {
+					case event, ok := <-events:
+						if !ok {
+							break collect
+						}
+						batch = append(batch, event)
+						if !timer.Stop() {
+							<-timer.C
+						}
+						timer.Reset(window)
+					case <-timer.C:
+						break collect
+					}
+				}
+				timer.Stop()
+			}
+			batches <- batch
+		}
+	}()
+	return batches
+}
@@ -0,0 +1,63 @@
+package entr
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatch(t *testing.T) {
+	events := make(chan Event)
+	batches := Batch(events, 50*time.Millisecond)
+	go func() {
+		events <- Event{Path: "/a", Op: "write"}
+		events <- Event{Path: "/b", Op: "create"}
+		time.Sleep(200 * time.Millisecond)
+		events <- Event{Path: "/c", Op: "remove"}
+		close(events)
+	}()
+	var got [][]string
+	for batch := range batches {
+		var paths []string
+		for _, event := range batch {
+			paths = append(paths, event.Path)
+		}
+		got = append(got, paths)
+	}
+	assert.Equal(t, [][]string{{"/a", "/b"}, {"/c"}}, got)
+}
+
+func TestEncoder(t *testing.T) {
+	var out bytes.Buffer
+	now := time.Date(2023, 10, 18, 12, 0, 0, 0, time.UTC)
+	encoder := NewEncoder(&out, "/repo")
+	require.NoError(t, encoder.WriteEvent(Event{Path: "/repo/src/main.go", Op: "write", Time: now}))
+	require.NoError(t, encoder.WriteBatch([]Event{
+		{Path: "/repo/src", Op: "create", Time: now, IsDir: true},
+		{Path: "/repo/a.txt", Op: "remove", Time: now.Add(time.Second)},
+	}))
+
+	decoder := json.NewDecoder(&out)
+	var event eventRecord
+	require.NoError(t, decoder.Decode(&event))
+	assert.Equal(t, "src/main.go", event.RelPath)
+	assert.Equal(t, "write", event.Event)
+
+	var batch batchRecord
+	require.NoError(t, decoder.Decode(&batch))
+	assert.Equal(t, now.Add(time.Second), batch.Time)
+	require.Len(t, batch.Events, 2)
+	assert.True(t, batch.Events[0].IsDir)
+	assert.Equal(t, "a.txt", batch.Events[1].RelPath)
+}
+
+func TestNotifyWatcherClose(t *testing.T) {
+	w, err := NewNotifyWatcher([]string{t.TempDir()}, nil)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	assert.NoError(t, w.Close(), "closing twice is harmless")
+}
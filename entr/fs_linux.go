@@ -0,0 +1,28 @@
+package entr
+
+import "syscall"
+
+// Magic numbers of filesystems on which inotify does not see changes made
+// by other hosts, taken from statfs(2).
+var pollFilesystems = map[uint32]string{
+	0x6969:     "nfs",
+	0x517b:     "smb",
+	0xff534d42: "cifs",
+	0xfe534d42: "smb2",
+	0x65735546: "fuse",
+	0x01021997: "9p",
+	0x786f4256: "vboxsf",
+	0x47504653: "gpfs",
+	0x0bd00bd0: "lustre",
+	0x5346414f: "afs",
+}
+
+func supportsNotify(path string) bool {
+	var stat syscall.Statfs_t
+	err := syscall.Statfs(path, &stat)
+	if err != nil {
+		return true
+	}
+	_, poll := pollFilesystems[uint32(stat.Type)]
+	return !poll
+}
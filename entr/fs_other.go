@@ -0,0 +1,7 @@
+//go:build !linux
+
+package entr
+
+func supportsNotify(_ string) bool {
+	return true
+}
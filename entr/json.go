@@ -0,0 +1,65 @@
+package entr
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"time"
+)
+
+type eventRecord struct {
+	Path    string    `json:"path"`
+	RelPath string    `json:"rel_path"`
+	Event   string    `json:"event"`
+	Time    time.Time `json:"time"`
+	IsDir   bool      `json:"is_dir"`
+}
+
+type batchRecord struct {
+	Time   time.Time     `json:"time"`
+	Events []eventRecord `json:"events"`
+}
+
+// Encoder writes events as JSONL records.
+// Relative paths in the records are relative to base.
+type Encoder struct {
+	enc  *json.Encoder
+	base string
+}
+
+// NewEncoder returns an encoder writing to w.
+func NewEncoder(w io.Writer, base string) *Encoder {
+	return &Encoder{enc: json.NewEncoder(w), base: base}
+}
+
+func (e *Encoder) record(event Event) eventRecord {
+	rel, err := filepath.Rel(e.base, event.Path)
+	if err != nil {
+		rel = event.Path
+	}
+	return eventRecord{
+		Path:    event.Path,
+		RelPath: rel,
+		Event:   event.Op,
+		Time:    event.Time,
+		IsDir:   event.IsDir,
+	}
+}
+
+// WriteEvent writes a single event as one record.
+func (e *Encoder) WriteEvent(event Event) error {
+	return e.enc.Encode(e.record(event))
+}
+
+// WriteBatch writes a batch of events as one record, timestamped with the
+// time of its last event.
+func (e *Encoder) WriteBatch(batch []Event) error {
+	record := batchRecord{Events: make([]eventRecord, len(batch))}
+	for i, event := range batch {
+		record.Events[i] = e.record(event)
+	}
+	if len(batch) > 0 {
+		record.Time = batch[len(batch)-1].Time
+	}
+	return e.enc.Encode(record)
+}
@@ -0,0 +1,184 @@
+package entr
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultPollInterval is used by the polling watcher if no interval is given.
+const DefaultPollInterval = time.Second
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+	isDir   bool
+	hash    [sha256.Size]byte
+}
+
+type pollWatcher struct {
+	paths     []string
+	recursive []string
+	interval  time.Duration
+	hash      bool
+	events    chan Event
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewPollWatcher returns a watcher that scans the watched paths every
+// interval and compares modification time and size, or the content hash if
+// hash is set. It works on every filesystem, including network and FUSE
+// mounts that do not deliver change notifications.
+func NewPollWatcher(paths, recursive []string, interval time.Duration, hash bool) (Watcher, error) {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	w := &pollWatcher{
+		paths:     paths,
+		recursive: recursive,
+		interval:  interval,
+		hash:      hash,
+		events:    make(chan Event, 10),
+		done:      make(chan struct{}),
+	}
+	for _, list := range [][]string{paths, recursive} {
+		for _, path := range list {
+			_, err := os.Stat(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to watch %s: %w", path, err)
+			}
+		}
+	}
+	go w.run(w.scan())
+	return w, nil
+}
+
+func (w *pollWatcher) run(previous map[string]fileState) {
+	defer close(w.events)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+		current := w.scan()
+		for _, event := range diff(previous, current) {
+			select {
+			case w.events <- event:
+			case <-w.done:
+				return
+			}
+		}
+		previous = current
+	}
+}
+
+func (w *pollWatcher) scan() map[string]fileState {
+	files := map[string]fileState{}
+	for _, path := range w.paths {
+		w.add(files, path)
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			w.add(files, filepath.Join(path, entry.Name()))
+		}
+	}
+	for _, root := range w.recursive {
+		_ = filepath.WalkDir(root, func(path string, _ fs.DirEntry, err error) error {
+			if err != nil {
+				// Vanished or unreadable entries are reported as removed.
+				return nil
+			}
+			w.add(files, path)
+			return nil
+		})
+	}
+	return files
+}
+
+func (w *pollWatcher) add(files map[string]fileState, path string) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return
+	}
+	state := fileState{
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		isDir:   info.IsDir(),
+	}
+	if w.hash && info.Mode().IsRegular() {
+		state.hash, err = hashFile(path)
+		if err != nil {
+			return
+		}
+		// Only the content decides whether a hashed file changed.
+		state.modTime = time.Time{}
+	}
+	files[path] = state
+}
+
+func hashFile(path string) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	file, err := os.Open(path)
+	if err != nil {
+		return sum, err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return sum, err
+	}
+	copy(sum[:], hash.Sum(nil))
+	return sum, nil
+}
+
+// diff returns the events turning previous into current, sorted by path.
+func diff(previous, current map[string]fileState) []Event {
+	now := time.Now()
+	var events []Event
+	for path, state := range current {
+		old, ok := previous[path]
+		switch {
+		case !ok:
+			events = append(events, Event{Path: path, Op: OpCreate, Time: now, IsDir: state.isDir})
+		case old.isDir != state.isDir:
+			events = append(events,
+				Event{Path: path, Op: OpRemove, Time: now, IsDir: old.isDir},
+				Event{Path: path, Op: OpCreate, Time: now, IsDir: state.isDir})
+		case !state.isDir && (!old.modTime.Equal(state.modTime) || old.size != state.size || old.hash != state.hash):
+			events = append(events, Event{Path: path, Op: OpWrite, Time: now})
+		}
+	}
+	for path, state := range previous {
+		if _, ok := current[path]; !ok {
+			events = append(events, Event{Path: path, Op: OpRemove, Time: now, IsDir: state.isDir})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Path < events[j].Path
+	})
+	return events
+}
+
+func (w *pollWatcher) Events() <-chan Event {
+	return w.events
+}
+
+func (w *pollWatcher) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.done)
+	})
+	return nil
+}
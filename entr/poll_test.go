@@ -0,0 +1,90 @@
+package entr
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func nextEvent(t *testing.T, w Watcher) Event {
+	t.Helper()
+	select {
+	case event := <-w.Events():
+		return event
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no event received")
+		return Event{}
+	}
+}
+
+func TestPollWatcher(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "sub", "file.txt")
+	require.NoError(t, os.Mkdir(filepath.Dir(file), 0o700))
+
+	w, err := NewPollWatcher(nil, []string{dir}, 10*time.Millisecond, false)
+	require.NoError(t, err)
+	defer w.Close()
+
+	require.NoError(t, os.WriteFile(file, []byte("a"), 0o600))
+	event := nextEvent(t, w)
+	assert.Equal(t, OpCreate, event.Op)
+	assert.Equal(t, file, event.Path)
+
+	require.NoError(t, os.WriteFile(file, []byte("ab"), 0o600))
+	event = nextEvent(t, w)
+	assert.Equal(t, OpWrite, event.Op)
+	assert.Equal(t, file, event.Path)
+
+	require.NoError(t, os.Remove(file))
+	event = nextEvent(t, w)
+	assert.Equal(t, OpRemove, event.Op)
+	assert.Equal(t, file, event.Path)
+}
+
+func TestPollWatcherHash(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file.txt")
+	require.NoError(t, os.WriteFile(file, []byte("a"), 0o600))
+
+	w, err := NewPollWatcher([]string{dir}, nil, 10*time.Millisecond, true)
+	require.NoError(t, err)
+	defer w.Close()
+
+	// Touching a file does not change its content.
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(file, later, later))
+	select {
+	case event := <-w.Events():
+		require.FailNow(t, "touch reported", "%v", event)
+	case <-time.After(200 * time.Millisecond):
+	}
+	require.NoError(t, os.WriteFile(file, []byte("b"), 0o600))
+	event := nextEvent(t, w)
+	assert.Equal(t, OpWrite, event.Op)
+	assert.Equal(t, file, event.Path)
+}
+
+func TestDiff(t *testing.T) {
+	now := time.Now()
+	previous := map[string]fileState{
+		"/a": {modTime: now, size: 1},
+		"/b": {modTime: now, size: 1},
+		"/c": {isDir: true},
+	}
+	current := map[string]fileState{
+		"/a": {modTime: now, size: 1},
+		"/b": {modTime: now, size: 2},
+		"/c": {size: 3},
+		"/d": {isDir: true},
+	}
+	var got []string
+	for _, event := range diff(previous, current) {
+		got = append(got, event.Op+" "+event.Path)
+	}
+	assert.Equal(t, []string{"write /b", "remove /c", "create /c", "create /d"}, got)
+}
@@ -0,0 +1,264 @@
+package entr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/interp"
+	"mvdan.cc/sh/v3/syntax"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var colors = []string{"36", "33", "35", "32", "34", "31"}
+
+// Runner runs all tasks of a config concurrently.
+type Runner struct {
+	config *Config
+	opts   WatchOptions
+	out    io.Writer
+	status io.Writer
+	logger *slog.Logger
+	color  bool
+
+	mu    sync.Mutex
+	tasks map[string]*taskState
+}
+
+type taskState struct {
+	name       string
+	task       *Task
+	trigger    chan struct{}
+	dependents []*taskState
+	out        *prefixWriter
+
+	// guarded by Runner.mu
+	state    string
+	duration time.Duration
+}
+
+// NewRunner returns a runner writing task output to out and the status
+// summary line to status. Output is colorized if color is set.
+func NewRunner(config *Config, opts WatchOptions, out, status io.Writer, color bool, logger *slog.Logger) *Runner {
+	r := &Runner{
+		config: config,
+		opts:   opts,
+		out:    out,
+		status: status,
+		logger: logger,
+		color:  color,
+		tasks:  map[string]*taskState{},
+	}
+	lock := &sync.Mutex{}
+	width := 0
+	for name := range config.Tasks {
+		width = max(width, len(name))
+	}
+	for i, name := range config.names() {
+		prefix := fmt.Sprintf("%-*s | ", width, name)
+		if color {
+			prefix = "\x1b[" + colors[i%len(colors)] + "m" + prefix + "\x1b[0m"
+		}
+		r.tasks[name] = &taskState{
+			name:    name,
+			task:    config.Tasks[name],
+			trigger: make(chan struct{}, 1),
+			out:     &prefixWriter{w: out, prefix: prefix, mu: lock},
+			state:   "idle",
+		}
+	}
+	for _, state := range r.tasks {
+		for _, dep := range state.task.After {
+			r.tasks[dep].dependents = append(r.tasks[dep].dependents, state)
+		}
+	}
+	return r
+}
+
+// Run starts watching and runs every task without dependencies once.
+// It blocks until ctx is cancelled.
+func (r *Runner) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, state := range r.tasks {
+		task := state.task
+		if len(task.Paths) == 0 && len(task.Recursive) == 0 {
+			continue
+		}
+		watcher, err := Watch(task.resolve(r.config.Dir, task.Paths), task.resolve(r.config.Dir, task.Recursive), r.opts)
+		if err != nil {
+			return fmt.Errorf("failed to watch paths of task %s: %w", state.name, err)
+		}
+		defer watcher.Close()
+		filtered := make(chan Event)
+		go func(state *taskState) {
+			defer close(filtered)
+			for event := range watcher.Events() {
+				if state.task.matches(r.config.Dir, event.Path) {
+					filtered <- event
+				}
+			}
+		}(state)
+		go func(state *taskState) {
+			for batch := range Batch(filtered, state.task.Debounce) {
+				r.logger.Debug("task triggered", "task", state.name, "events", len(batch), "path", batch[0].Path)
+				state.fire()
+			}
+		}(state)
+	}
+	for _, state := range r.tasks {
+		if len(state.task.After) == 0 {
+			state.fire()
+		}
+		wg.Add(1)
+		go func(state *taskState) {
+			defer wg.Done()
+			r.loop(ctx, state)
+		}(state)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+func (s *taskState) fire() {
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+func (r *Runner) loop(ctx context.Context, state *taskState) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-state.trigger:
+		}
+		for rerun := true; rerun; {
+			rerun = false
+			runCtx, cancel := context.WithCancel(ctx)
+			done := make(chan error, 1)
+			start := time.Now()
+			r.setState(state, "running", 0)
+			go func() {
+				done <- r.execute(runCtx, state)
+			}()
+		wait:
+			for {
+				select {
+				case err := <-done:
+					r.finish(ctx, state, err, time.Since(start))
+					break wait
+				case <-state.trigger:
+					switch state.task.Restart {
+					case RestartKill:
+						cancel()
+						<-done
+						r.setState(state, "restarting", time.Since(start))
+						rerun = true
+						break wait
+					case RestartQueue:
+						rerun = true
+					}
+				}
+			}
+			cancel()
+		}
+	}
+}
+
+func (r *Runner) finish(ctx context.Context, state *taskState, err error, duration time.Duration) {
+	status, exited := interp.IsExitStatus(err)
+	switch {
+	case ctx.Err() != nil:
+		r.setState(state, "stopped", duration)
+	case err == nil:
+		r.setState(state, "ok", duration)
+		for _, dependent := range state.dependents {
+			dependent.fire()
+		}
+	case exited:
+		r.setState(state, fmt.Sprintf("failed (exit %d)", status), duration)
+	default:
+		r.setState(state, "failed", duration)
+		fmt.Fprintf(state.out, "%v\n", err)
+	}
+}
+
+func (r *Runner) execute(ctx context.Context, state *taskState) error {
+	defer state.out.Flush()
+	file, err := syntax.NewParser().Parse(strings.NewReader(state.task.Command), state.name)
+	if err != nil {
+		return fmt.Errorf("failed to parse command: %w", err)
+	}
+	runner, err := interp.New(
+		interp.Env(expand.ListEnviron(os.Environ()...)),
+		interp.Dir(r.config.Dir),
+		interp.StdIO(nil, state.out, state.out),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create runner: %w", err)
+	}
+	return runner.Run(ctx, file)
+}
+
+func (r *Runner) setState(state *taskState, s string, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state.state = s
+	state.duration = duration
+	var parts []string
+	for _, name := range r.config.names() {
+		task := r.tasks[name]
+		part := name + ": " + task.state
+		if task.duration > 0 {
+			part += " " + task.duration.Round(time.Millisecond).String()
+		}
+		parts = append(parts, part)
+	}
+	line := strings.Join(parts, " · ")
+	if r.color {
+		line = "\x1b[1m" + line + "\x1b[0m"
+	}
+	fmt.Fprintln(r.status, line)
+}
+
+// prefixWriter prefixes every line written to it and writes whole lines only,
+// so output of concurrently running tasks does not interleave mid-line.
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+	mu     *sync.Mutex
+	buf    bytes.Buffer
+}
+
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.buf.Write(data)
+	for {
+		i := bytes.IndexByte(p.buf.Bytes(), '\n')
+		if i < 0 {
+			return len(data), nil
+		}
+		line := p.buf.Next(i + 1)
+		_, err := io.WriteString(p.w, p.prefix+string(line))
+		if err != nil {
+			return len(data), err
+		}
+	}
+}
+
+// Flush writes out a trailing partial line.
+func (p *prefixWriter) Flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.buf.Len() > 0 {
+		_, _ = io.WriteString(p.w, p.prefix+p.buf.String()+"\n")
+		p.buf.Reset()
+	}
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ktr0731/go-fuzzyfinder"
+	"github.com/urfave/cli/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"tasadar.net/tionis/shell-tools/git"
+)
+
+func gitCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "git",
+		Usage: "git helpers",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "root",
+				Usage: "print the top-level directory of the current worktree",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "superproject",
+						Aliases: []string{"s"},
+						Usage:   "in a submodule, print the root of the outermost superproject",
+					},
+					&cli.BoolFlag{
+						Name:    "main",
+						Aliases: []string{"m"},
+						Usage:   "in a linked worktree, print the root of the main worktree",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					var root string
+					var err error
+					if c.Bool("main") {
+						root, err = git.MainWorktree(".")
+					} else {
+						root, err = git.Root(".", c.Bool("superproject"))
+					}
+					if err != nil {
+						return err
+					}
+					fmt.Println(root)
+					return nil
+				},
+			},
+			{
+				Name:      "sparse-clone",
+				Usage:     "clone without blobs and pick the directories to check out",
+				ArgsUsage: "url [dir]",
+				Action: func(c *cli.Context) error {
+					if c.Args().Len() == 0 || c.Args().Len() > 2 {
+						return errors.New("expected a url and optionally a directory")
+					}
+					url := c.Args().First()
+					dir := c.Args().Get(1)
+					if dir == "" {
+						dir = git.CloneDir(url)
+					}
+					err := git.SparseClone(url, dir)
+					if err != nil {
+						return err
+					}
+					dirs, err := pickSparseDirs(dir)
+					if errors.Is(err, fuzzyfinder.ErrAbort) {
+						// Still check out the top-level files, directories
+						// can be added with sparse-checkout later.
+						logger.Warn("no directories picked, checking out top-level files only")
+					} else if err != nil {
+						return err
+					}
+					err = git.SetSparseDirs(dir, dirs)
+					if err != nil {
+						return err
+					}
+					return git.Checkout(dir)
+				},
+			},
+			{
+				Name:  "sparse-checkout",
+				Usage: "pick the directories of the sparse checkout of the current repository",
+				Action: func(c *cli.Context) error {
+					root, err := git.Root(".", false)
+					if err != nil {
+						return err
+					}
+					dirs, err := pickSparseDirs(root)
+					if errors.Is(err, fuzzyfinder.ErrAbort) {
+						return nil
+					}
+					if err != nil {
+						return err
+					}
+					return git.SetSparseDirs(root, dirs)
+				},
+			},
+			skmCommand(),
+		},
+	}
+}
+
+func skmCommand() *cli.Command {
+	signingFlags := []cli.Flag{
+		&cli.BoolFlag{
+			Name:    "global",
+			Aliases: []string{"g"},
+			Usage:   "configure signing for all repositories of the user",
+		},
+		&cli.StringFlag{
+			Name:    "allowed-signers",
+			Aliases: []string{"a"},
+			Usage:   "allowed signers file to verify signatures with",
+		},
+	}
+	configure := func(c *cli.Context, key string) error {
+		err := git.ConfigureSigning(".", key, git.SigningOptions{
+			Global:         c.Bool("global"),
+			AllowedSigners: c.String("allowed-signers"),
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "commits are now signed with %s\n", key)
+		return nil
+	}
+	return &cli.Command{
+		Name:  "skm",
+		Usage: "manage SSH commit signing keys and allowed signers",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "use",
+				Usage:     "sign commits with an existing SSH key, picked from ~/.ssh if not given",
+				ArgsUsage: "[public key]",
+				Flags:     signingFlags,
+				Action: func(c *cli.Context) error {
+					key := c.Args().First()
+					if key == "" {
+						keys, err := git.PublicKeys()
+						if err != nil {
+							return err
+						}
+						if len(keys) == 0 {
+							return errors.New("no public keys in ~/.ssh, create one with git skm generate")
+						}
+						index, err := fuzzyfinder.Find(keys, func(i int) string {
+							return keys[i]
+						}, fuzzyfinder.WithPreviewWindow(func(i, width, height int) string {
+							if i < 0 {
+								return ""
+							}
+							data, err := os.ReadFile(keys[i])
+							if err != nil {
+								return err.Error()
+							}
+							return string(data)
+						}))
+						if err != nil {
+							return err
+						}
+						key = keys[index]
+					}
+					return configure(c, key)
+				},
+			},
+			{
+				Name:  "generate",
+				Usage: "generate an ed25519 key and sign commits with it",
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:    "file",
+						Aliases: []string{"f"},
+						Usage:   "path of the private key, defaults to ~/.ssh/id_ed25519_signing",
+					},
+					&cli.StringFlag{
+						Name:    "comment",
+						Aliases: []string{"C"},
+						Usage:   "key comment, defaults to the git user.email",
+					},
+				}, signingFlags...),
+				Action: func(c *cli.Context) error {
+					path := c.String("file")
+					if path == "" {
+						home, err := os.UserHomeDir()
+						if err != nil {
+							return fmt.Errorf("failed to get home directory: %w", err)
+						}
+						path = filepath.Join(home, ".ssh", "id_ed25519_signing")
+					}
+					comment := c.String("comment")
+					if comment == "" {
+						// An unset email leaves the comment to ssh-keygen.
+						comment, _ = git.Config(".", "user.email")
+					}
+					key, err := git.GenerateKey(path, comment)
+					if err != nil {
+						return err
+					}
+					return configure(c, key)
+				},
+			},
+			{
+				Name:      "allowed-signers",
+				Usage:     "build an allowed signers file from a team roster in JSON, YAML or TOML",
+				ArgsUsage: "roster",
+				Description: "The roster lists members with name, email and keys, the public keys they\n" +
+					"sign with, either at the top level or under members, e.g. in TOML:\n\n" +
+					"[[members]]\nname = \"Alice\"\nemail = \"alice@example.com\"\nkeys = [\"ssh-ed25519 AAAA...\"]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "file to write instead of stdout",
+					},
+					&cli.BoolFlag{
+						Name:  "configure",
+						Usage: "verify signatures against the written file, requires --output",
+					},
+					&cli.BoolFlag{
+						Name:    "global",
+						Aliases: []string{"g"},
+						Usage:   "with --configure, configure it for all repositories of the user",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if c.Args().Len() != 1 {
+						return errors.New("expected a roster file")
+					}
+					roster, err := git.LoadRoster(c.Args().First())
+					if err != nil {
+						return err
+					}
+					output := c.String("output")
+					if output == "" {
+						if c.Bool("configure") {
+							return errors.New("--configure requires --output")
+						}
+						fmt.Print(roster.AllowedSigners())
+						return nil
+					}
+					err = roster.WriteAllowedSigners(output)
+					if err != nil {
+						return err
+					}
+					if !c.Bool("configure") {
+						return nil
+					}
+					return git.ConfigureAllowedSigners(".", output, c.Bool("global"))
+				},
+			},
+			{
+				Name:      "verify",
+				Usage:     "report which roster member signed each commit of a range",
+				ArgsUsage: "[range]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "roster",
+						Aliases:  []string{"r"},
+						Usage:    "team roster in JSON, YAML or TOML",
+						Required: true,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					revisionRange := c.Args().First()
+					if revisionRange == "" {
+						revisionRange = "HEAD"
+					}
+					roster, err := git.LoadRoster(c.String("roster"))
+					if err != nil {
+						return err
+					}
+					signatures, err := git.Verify(".", revisionRange, roster)
+					if err != nil {
+						return err
+					}
+					unverified := 0
+					for _, signature := range signatures {
+						if !signature.Verified() {
+							unverified++
+						}
+						fmt.Printf("%.12s %s: %s\n", signature.Commit, signature.Describe(), signature.Subject)
+					}
+					if unverified > 0 {
+						return cli.Exit(fmt.Sprintf("%d of %d commits are not signed by roster members", unverified, len(signatures)), 1)
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// pickSparseDirs lets the user toggle directories of the repository at dir
+// in and out of its sparse checkout and returns the resulting selection.
+// The finder can not preselect items, so selected directories are marked
+// and picking them removes them.
+func pickSparseDirs(dir string) ([]string, error) {
+	dirs, err := git.Dirs(dir, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if len(dirs) == 0 {
+		return nil, errors.New("repository has no directories")
+	}
+	current, err := git.SparseDirs(dir)
+	if err != nil {
+		return nil, err
+	}
+	selected := map[string]bool{}
+	for _, path := range current {
+		selected[path] = true
+	}
+	indices, err := fuzzyfinder.FindMulti(dirs, func(i int) string {
+		if selected[dirs[i]] {
+			return "[x] " + dirs[i]
+		}
+		return "[ ] " + dirs[i]
+	}, fuzzyfinder.WithHeader("tab toggles directories, [x] marks the current sparse checkout"),
+		fuzzyfinder.WithPreviewWindow(func(i, width, height int) string {
+			if len(current) == 0 {
+				return "only top-level files are checked out"
+			}
+			return "checked out:\n" + strings.Join(current, "\n")
+		}))
+	if err != nil {
+		return nil, err
+	}
+	toggled := make([]string, len(indices))
+	for i, index := range indices {
+		toggled[i] = dirs[index]
+	}
+	dirs = git.Toggle(current, toggled)
+	fmt.Fprintf(os.Stderr, "sparse checkout: %s\n", strings.Join(dirs, " "))
+	return dirs, nil
+}
@@ -0,0 +1,161 @@
+// Package git wraps the git commands behind the git helper commands.
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// run runs git with args in dir and returns its trimmed output.
+func run(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			return "", fmt.Errorf("git %s failed: %w", args[0], err)
+		}
+		return "", fmt.Errorf("git %s failed: %s", args[0], message)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// lines splits output into its lines, none for empty output.
+func lines(output string) []string {
+	if output == "" {
+		return nil
+	}
+	return strings.Split(output, "\n")
+}
+
+// Root returns the top-level directory of the worktree containing dir. In a
+// submodule this is the root of the submodule, unless superproject is set,
+// in which case the root of the outermost superproject is returned.
+func Root(dir string, superproject bool) (string, error) {
+	root, err := run(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	for superproject {
+		parent, err := run(root, "rev-parse", "--show-superproject-working-tree")
+		if err != nil {
+			return "", err
+		}
+		if parent == "" {
+			break
+		}
+		root = parent
+	}
+	return filepath.FromSlash(root), nil
+}
+
+// MainWorktree returns the top-level directory of the main worktree of the
+// repository containing dir, which differs from [Root] in linked worktrees.
+func MainWorktree(dir string) (string, error) {
+	output, err := run(dir, "worktree", "list", "--porcelain")
+	if err != nil {
+		return "", err
+	}
+	// The main worktree is always listed first.
+	first, _, _ := strings.Cut(output, "\n")
+	path, ok := strings.CutPrefix(first, "worktree ")
+	if !ok {
+		return "", fmt.Errorf("unexpected worktree list: %q", first)
+	}
+	return filepath.FromSlash(path), nil
+}
+
+// Dirs returns all directories in the tree of ref, which works without
+// blobs, so in blobless clones before any checkout.
+func Dirs(dir, ref string) ([]string, error) {
+	output, err := run(dir, "ls-tree", "-r", "-d", "--name-only", ref)
+	if err != nil {
+		return nil, err
+	}
+	return lines(output), nil
+}
+
+// SparseDirs returns the directories of the cone mode sparse checkout of the
+// repository at dir, or nil if it is not sparse.
+func SparseDirs(dir string) ([]string, error) {
+	enabled, err := run(dir, "config", "--bool", "core.sparseCheckout")
+	if err != nil || enabled != "true" {
+		// An unset config lets git config fail.
+		return nil, nil
+	}
+	output, err := run(dir, "sparse-checkout", "list")
+	if err != nil {
+		return nil, err
+	}
+	return lines(output), nil
+}
+
+// SetSparseDirs makes the checkout of the repository at dir a cone mode
+// sparse checkout of dirs, along with all files at the top level.
+func SetSparseDirs(dir string, dirs []string) error {
+	_, err := run(dir, append([]string{"sparse-checkout", "set", "--cone", "--"}, dirs...)...)
+	return err
+}
+
+// SparseClone clones url into dir without blobs and without checking out
+// any files, so the paths to check out can be chosen with [SetSparseDirs]
+// before calling [Checkout].
+func SparseClone(url, dir string) error {
+	cmd := exec.Command("git", "clone", "--filter=blob:none", "--no-checkout", url, dir)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf("git clone failed: %w", err)
+	}
+	return nil
+}
+
+// Checkout checks out the current branch of the repository at dir.
+func Checkout(dir string) error {
+	_, err := run(dir, "checkout")
+	return err
+}
+
+// CloneDir returns the directory git clone uses for url.
+func CloneDir(url string) string {
+	name := strings.TrimRight(url, "/")
+	name = strings.TrimSuffix(name, "/.git")
+	if i := strings.LastIndexAny(name, "/:"); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.TrimSuffix(name, ".git")
+	if name == "" {
+		return "repo"
+	}
+	return name
+}
+
+// Toggle returns current with the paths of toggled removed if they are in
+// current, and added otherwise, sorted.
+func Toggle(current, toggled []string) []string {
+	set := map[string]bool{}
+	for _, path := range current {
+		set[path] = true
+	}
+	for _, path := range toggled {
+		set[path] = !set[path]
+	}
+	var result []string
+	for path, selected := range set {
+		if selected {
+			result = append(result, path)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
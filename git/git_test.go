@@ -0,0 +1,141 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gitRun runs git in dir with a fixed identity.
+func gitRun(t *testing.T, dir string, args ...string) {
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "protocol.file.allow=always"}, args...)...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+}
+
+// bareRepo returns a bare repository with the files committed.
+func bareRepo(t *testing.T, files ...string) string {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	for _, file := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(src, filepath.Dir(file)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(src, file), []byte(file), 0o644))
+	}
+	gitRun(t, src, "init", "-q", "-b", "main")
+	gitRun(t, src, "add", ".")
+	gitRun(t, src, "commit", "-q", "-m", "init")
+	gitRun(t, dir, "clone", "-q", "--bare", "src", "bare.git")
+	return filepath.Join(dir, "bare.git")
+}
+
+func TestSparseClone(t *testing.T) {
+	bare := bareRepo(t, "README", "apps/web/index.html", "apps/api/main.go", "libs/ui/button.go")
+	dir := filepath.Join(t.TempDir(), CloneDir("file://"+bare))
+	assert.Equal(t, "bare", filepath.Base(dir))
+	require.NoError(t, SparseClone("file://"+bare, dir))
+
+	dirs, err := Dirs(dir, "HEAD")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"apps", "apps/api", "apps/web", "libs", "libs/ui"}, dirs)
+	current, err := SparseDirs(dir)
+	require.NoError(t, err)
+	assert.Empty(t, current)
+
+	require.NoError(t, SetSparseDirs(dir, []string{"apps/api"}))
+	require.NoError(t, Checkout(dir))
+	assert.FileExists(t, filepath.Join(dir, "README"))
+	assert.FileExists(t, filepath.Join(dir, "apps", "api", "main.go"))
+	assert.NoFileExists(t, filepath.Join(dir, "apps", "web", "index.html"))
+
+	current, err = SparseDirs(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"apps/api"}, current)
+	require.NoError(t, SetSparseDirs(dir, Toggle(current, []string{"apps/api", "libs/ui"})))
+	assert.NoFileExists(t, filepath.Join(dir, "apps", "api", "main.go"))
+	assert.FileExists(t, filepath.Join(dir, "libs", "ui", "button.go"))
+}
+
+func TestRoot(t *testing.T) {
+	outer := t.TempDir()
+	gitRun(t, outer, "clone", "-q", bareRepo(t, "src/main.go"), "repo")
+	repo, err := filepath.EvalSymlinks(filepath.Join(outer, "repo"))
+	require.NoError(t, err)
+	gitRun(t, repo, "submodule", "add", "-q", bareRepo(t, "lib/lib.go"), "vendor/lib")
+	gitRun(t, repo, "worktree", "add", "-q", filepath.Join(outer, "feature"))
+
+	root, err := Root(filepath.Join(repo, "src"), false)
+	require.NoError(t, err)
+	assert.Equal(t, repo, root)
+
+	submodule := filepath.Join(repo, "vendor", "lib")
+	root, err = Root(filepath.Join(submodule, "lib"), false)
+	require.NoError(t, err)
+	assert.Equal(t, submodule, root)
+	root, err = Root(filepath.Join(submodule, "lib"), true)
+	require.NoError(t, err)
+	assert.Equal(t, repo, root)
+
+	worktree := filepath.Join(filepath.Dir(repo), "feature")
+	root, err = Root(filepath.Join(worktree, "src"), false)
+	require.NoError(t, err)
+	assert.Equal(t, worktree, root)
+	main, err := MainWorktree(worktree)
+	require.NoError(t, err)
+	assert.Equal(t, repo, main)
+
+	_, err = Root(t.TempDir(), false)
+	assert.ErrorContains(t, err, "not a git repository")
+}
+
+// sshKey generates an unencrypted key in dir and returns the path of its
+// public key along with its content.
+func sshKey(t *testing.T, dir, name string) (string, string) {
+	path := filepath.Join(dir, name)
+	output, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-C", name, "-f", path).CombinedOutput()
+	require.NoError(t, err, string(output))
+	public, err := os.ReadFile(path + ".pub")
+	require.NoError(t, err)
+	return path + ".pub", strings.TrimSpace(string(public))
+}
+
+func TestVerify(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not found")
+	}
+	dir := t.TempDir()
+	alicePath, aliceKey := sshKey(t, dir, "alice")
+	bobPath, _ := sshKey(t, dir, "bob")
+	roster, err := ParseRoster(strings.NewReader("- name: Alice\n  email: alice@example.com\n  keys: ['"+aliceKey+"']\n"), "yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com namespaces=\"git\" "+aliceKey+"\n", roster.AllowedSigners())
+	tomlRoster, err := ParseRoster(strings.NewReader("[[members]]\nname = \"Alice\"\nemail = \"alice@example.com\"\nkeys = ['"+aliceKey+"']\n"), "toml")
+	require.NoError(t, err)
+	assert.Equal(t, roster, tomlRoster)
+	_, err = ParseRoster(strings.NewReader(`[{"email": "bob@example.com", "keys": ["ssh-ed25519 invalid"]}]`), "json")
+	assert.Error(t, err)
+	_, err = ParseRoster(strings.NewReader(`{"team": []}`), "json")
+	assert.ErrorContains(t, err, "list of members")
+
+	repo := filepath.Join(dir, "repo")
+	gitRun(t, dir, "init", "-q", "repo")
+	gitRun(t, repo, "commit", "-q", "--allow-empty", "-m", "unsigned")
+	require.NoError(t, ConfigureSigning(repo, alicePath, SigningOptions{}))
+	gitRun(t, repo, "commit", "-q", "--allow-empty", "-m", "by alice")
+	gitRun(t, repo, "-c", "user.signingkey="+bobPath, "commit", "-q", "--allow-empty", "-m", "by bob")
+
+	signatures, err := Verify(repo, "HEAD", roster)
+	require.NoError(t, err)
+	require.Len(t, signatures, 3)
+	assert.Equal(t, "by bob", signatures[0].Subject)
+	assert.False(t, signatures[0].Verified())
+	assert.Contains(t, signatures[0].Describe(), "unknown key")
+	assert.True(t, signatures[1].Verified())
+	assert.Equal(t, "signed by Alice <alice@example.com>", signatures[1].Describe())
+	assert.Equal(t, "unsigned", signatures[2].Describe())
+}
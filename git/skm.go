@@ -0,0 +1,286 @@
+package git
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"tasadar.net/tionis/shell-tools/convert"
+)
+
+// Member is a person of a roster along with the SSH keys they sign with.
+type Member struct {
+	Name  string   `json:"name"`
+	Email string   `json:"email"`
+	Keys  []string `json:"keys"`
+}
+
+// Roster lists the members allowed to sign commits.
+type Roster []Member
+
+// ParseRoster reads a roster in the given convert input format and checks
+// that every member has an email and valid public keys. A roster is a list of
+// members, or an object listing them under members, as TOML needs.
+func ParseRoster(r io.Reader, format string) (Roster, error) {
+	data, err := convert.Decode(format, nil, r)
+	if err != nil {
+		return nil, err
+	}
+	// The generic structure of the convert formats is mapped onto the
+	// roster through its JSON representation.
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read roster: %w", err)
+	}
+	var roster Roster
+	if json.Unmarshal(encoded, &roster) != nil {
+		var object struct {
+			Members Roster `json:"members"`
+		}
+		err = json.Unmarshal(encoded, &object)
+		if err != nil || object.Members == nil {
+			return nil, errors.New("roster must be a list of members or an object with members")
+		}
+		roster = object.Members
+	}
+	for i, member := range roster {
+		if member.Email == "" {
+			return nil, fmt.Errorf("member %d has no email", i+1)
+		}
+		if len(member.Keys) == 0 {
+			return nil, fmt.Errorf("member %s has no keys", member.Email)
+		}
+		for _, key := range member.Keys {
+			_, _, _, _, err = ssh.ParseAuthorizedKey([]byte(key))
+			if err != nil {
+				return nil, fmt.Errorf("member %s has an invalid key: %w", member.Email, err)
+			}
+		}
+	}
+	return roster, nil
+}
+
+// LoadRoster reads the roster file at path, its format guessed from its
+// extension.
+func LoadRoster(path string) (Roster, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open roster: %w", err)
+	}
+	defer file.Close()
+	format := "json"
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		format = "yaml"
+	case ".toml":
+		format = "toml"
+	}
+	return ParseRoster(file, format)
+}
+
+// AllowedSigners returns the roster in the allowed signers format of
+// ssh-keygen, a line per key limited to the git namespace, sorted by email.
+func (r Roster) AllowedSigners() string {
+	var entries []string
+	for _, member := range r {
+		for _, key := range member.Keys {
+			entries = append(entries, fmt.Sprintf("%s namespaces=\"git\" %s\n", member.Email, strings.TrimSpace(key)))
+		}
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, "")
+}
+
+// WriteAllowedSigners writes the allowed signers file of the roster to path.
+func (r Roster) WriteAllowedSigners(path string) error {
+	err := os.WriteFile(path, []byte(r.AllowedSigners()), 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write allowed signers: %w", err)
+	}
+	return nil
+}
+
+// fingerprints maps the SHA256 fingerprints of all keys to their members.
+func (r Roster) fingerprints() map[string]*Member {
+	members := map[string]*Member{}
+	for i := range r {
+		for _, key := range r[i].Keys {
+			// Keys are checked when parsing the roster.
+			if publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key)); err == nil {
+				members[ssh.FingerprintSHA256(publicKey)] = &r[i]
+			}
+		}
+	}
+	return members
+}
+
+// PublicKeys returns the public keys in the ~/.ssh directory.
+func PublicKeys() ([]string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	keys, err := filepath.Glob(filepath.Join(home, ".ssh", "*.pub"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys: %w", err)
+	}
+	return keys, nil
+}
+
+// GenerateKey generates an ed25519 key at path, asking for its passphrase
+// on the terminal, and returns the path of its public key.
+func GenerateKey(path, comment string) (string, error) {
+	args := []string{"-t", "ed25519", "-f", path}
+	if comment != "" {
+		args = append(args, "-C", comment)
+	}
+	cmd := exec.Command("ssh-keygen", args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("ssh-keygen failed: %w", err)
+	}
+	return path + ".pub", nil
+}
+
+// SigningOptions configure [ConfigureSigning].
+type SigningOptions struct {
+	// Global sets the user config instead of the one of the repository.
+	Global bool
+	// AllowedSigners is the allowed signers file used for verification,
+	// left unchanged if empty.
+	AllowedSigners string
+}
+
+// ConfigureSigning makes git sign commits of the repository at dir with the
+// SSH key, given by the path of its public key.
+func ConfigureSigning(dir, key string, opts SigningOptions) error {
+	key, err := filepath.Abs(key)
+	if err != nil {
+		return fmt.Errorf("failed to resolve key: %w", err)
+	}
+	_, err = os.Stat(key)
+	if err != nil {
+		return fmt.Errorf("failed to find key: %w", err)
+	}
+	settings := [][2]string{
+		{"gpg.format", "ssh"},
+		{"user.signingkey", key},
+		{"commit.gpgsign", "true"},
+	}
+	for _, setting := range settings {
+		err = setConfig(dir, opts.Global, setting[0], setting[1])
+		if err != nil {
+			return err
+		}
+	}
+	if opts.AllowedSigners == "" {
+		return nil
+	}
+	return ConfigureAllowedSigners(dir, opts.AllowedSigners, opts.Global)
+}
+
+// ConfigureAllowedSigners makes git verify SSH signatures in the repository
+// at dir against the allowed signers file at path.
+func ConfigureAllowedSigners(dir, path string, global bool) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve allowed signers: %w", err)
+	}
+	return setConfig(dir, global, "gpg.ssh.allowedSignersFile", path)
+}
+
+// Config returns the value of the git config key in the repository at dir.
+func Config(dir, key string) (string, error) {
+	return run(dir, "config", key)
+}
+
+func setConfig(dir string, global bool, key, value string) error {
+	scope := "--local"
+	if global {
+		scope = "--global"
+	}
+	_, err := run(dir, "config", scope, key, value)
+	return err
+}
+
+// Signature is the verification result of a commit.
+type Signature struct {
+	Commit  string
+	Subject string
+	// Status is the signature status of git log %G?: G for a good, B for a
+	// bad signature and N for none. Any other status means the signature
+	// could not be verified.
+	Status string
+	// Fingerprint is the fingerprint of the signing key, if signed.
+	Fingerprint string
+	// Member is the roster member owning the signing key, if any.
+	Member *Member
+}
+
+// Verified reports whether the commit has a good signature by a member.
+func (s Signature) Verified() bool {
+	return s.Status == "G" && s.Member != nil
+}
+
+// Describe explains the signature status.
+func (s Signature) Describe() string {
+	switch {
+	case s.Verified():
+		return fmt.Sprintf("signed by %s <%s>", s.Member.Name, s.Member.Email)
+	case s.Status == "N":
+		return "unsigned"
+	case s.Status == "B":
+		return "bad signature"
+	case s.Member == nil && s.Fingerprint != "":
+		return "signed by unknown key " + s.Fingerprint
+	default:
+		return "unverifiable signature"
+	}
+}
+
+// Verify checks the signatures of the commits in revisionRange of the
+// repository at dir against the roster, newest first.
+func Verify(dir, revisionRange string, roster Roster) ([]Signature, error) {
+	file, err := os.CreateTemp("", "allowed_signers")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create allowed signers: %w", err)
+	}
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(roster.AllowedSigners())
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write allowed signers: %w", err)
+	}
+	output, err := run(dir, "-c", "gpg.ssh.allowedSignersFile="+file.Name(),
+		"log", "--format=%H%x1f%G?%x1f%GK%x1f%s", revisionRange, "--")
+	if err != nil {
+		return nil, err
+	}
+	members := roster.fingerprints()
+	var signatures []Signature
+	for _, line := range lines(output) {
+		fields := strings.SplitN(line, "\x1f", 4)
+		if len(fields) != 4 {
+			return nil, errors.New("unexpected git log output: " + line)
+		}
+		signatures = append(signatures, Signature{
+			Commit:      fields[0],
+			Status:      fields[1],
+			Fingerprint: fields[2],
+			Subject:     fields[3],
+			Member:      members[fields[2]],
+		})
+	}
+	return signatures, nil
+}
@@ -4,17 +4,23 @@ go 1.21.1
 
 require (
 	filippo.io/age v1.1.1
+	github.com/BurntSushi/toml v1.3.2
 	github.com/google/uuid v1.3.1
 	github.com/ktr0731/go-fuzzyfinder v0.7.0
 	github.com/rjeczalik/notify v0.9.3
 	github.com/stretchr/testify v1.8.4
+	github.com/tkuchiki/go-timezone v0.2.2
 	github.com/urfave/cli/v2 v2.25.7
 	gitlab.com/tozd/regex2json v0.11.0
 	golang.design/x/clipboard v0.7.0
+	golang.org/x/crypto v0.4.0
+	golang.org/x/term v0.8.0
+	gopkg.in/yaml.v3 v3.0.1
 	mvdan.cc/sh/v3 v3.7.0
 )
 
 require (
+	filippo.io/edwards25519 v1.0.0 // indirect
 	github.com/cpuguy83/go-md2man/v2 v2.0.2 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/gdamore/encoding v1.0.0 // indirect
@@ -27,15 +33,11 @@ require (
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/rivo/uniseg v0.4.2 // indirect
 	github.com/russross/blackfriday/v2 v2.1.0 // indirect
-	github.com/tkuchiki/go-timezone v0.2.2 // indirect
 	github.com/xrash/smetrics v0.0.0-20201216005158-039620a65673 // indirect
-	golang.org/x/crypto v0.4.0 // indirect
 	golang.org/x/exp v0.0.0-20190731235908-ec7cb31e5a56 // indirect
 	golang.org/x/image v0.6.0 // indirect
 	golang.org/x/mobile v0.0.0-20230301163155-e0f57694e12c // indirect
 	golang.org/x/sync v0.2.0 // indirect
 	golang.org/x/sys v0.12.0 // indirect
-	golang.org/x/term v0.8.0 // indirect
 	golang.org/x/text v0.8.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -2,35 +2,26 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
-	"filippo.io/age"
 	"fmt"
 	"github.com/google/uuid"
-	"github.com/ktr0731/go-fuzzyfinder"
-	"github.com/rjeczalik/notify"
 	"github.com/urfave/cli/v2"
 	"golang.design/x/clipboard"
 	"io"
 	"log"
 	"log/slog"
-	"mvdan.cc/sh/v3/expand"
-	"mvdan.cc/sh/v3/interp"
-	"mvdan.cc/sh/v3/syntax"
 	"os"
 	"os/exec"
-	"strings"
+	"os/signal"
 	"tasadar.net/tionis/shell-tools/convert"
+	"tasadar.net/tionis/shell-tools/entr"
+	"tasadar.net/tionis/shell-tools/ts"
 )
 
-type quickCommand struct {
-	Description string `json:"description"`
-	Command     string `json:"command"`
-}
+var logger *slog.Logger
 
 func main() {
 	log.SetFlags(log.Lshortfile | log.LstdFlags)
-	var logger *slog.Logger
 
 	//homeDir, err := os.UserHomeDir()
 	//if err != nil {
@@ -61,9 +52,11 @@ func main() {
 			if logLevel == slog.LevelDebug {
 				addSource = true
 			}
+			// Logs go to stderr, so they do not mix with the output of
+			// commands like entr --events json.
 			logger = slog.New(
 				slog.NewTextHandler(
-					os.Stdout,
+					os.Stderr,
 					&slog.HandlerOptions{
 						AddSource: addSource,
 						Level:     logLevel,
@@ -87,16 +80,30 @@ func main() {
 						Aliases: []string{"ta"},
 						Usage:   "arguments for the to format",
 					},
+					&cli.StringSliceFlag{
+						Name:    "transform",
+						Aliases: []string{"t"},
+						Usage:   "transform a field with regex2json operators, given as path=operators, e.g. payload=json___decode__base64",
+					},
 				},
 				Action: func(c *cli.Context) error {
 					if c.Args().Len() != 2 {
 						return errors.New("invalid number of arguments")
 					}
+					var transforms []convert.Transform
+					for _, spec := range c.StringSlice("transform") {
+						transform, err := convert.ParseTransform(spec)
+						if err != nil {
+							return err
+						}
+						transforms = append(transforms, transform)
+					}
 					return convert.Convert(
 						c.Args().Get(0),
 						c.StringSlice("from-args"),
 						c.Args().Get(1),
 						c.StringSlice("to-args"),
+						transforms,
 						os.Stdin, os.Stdout)
 				},
 			},
@@ -111,59 +118,141 @@ func main() {
 					},
 					&cli.StringFlag{
 						Name:  "this",
-						Usage: "just watch working dir, nothing else",
+						Usage: "deprecated, has no effect: the working dir is watched unless paths are given",
 					},
 					&cli.StringSliceFlag{
 						Name:    "recursive",
 						Aliases: []string{"r"},
 						Usage:   "a path to watch recursively",
 					},
+					&cli.DurationFlag{
+						Name:    "debounce",
+						Aliases: []string{"d"},
+						Usage:   "group events arriving within this duration into one batch",
+					},
+					&cli.StringFlag{
+						Name:  "events",
+						Usage: "instead of running a command, write events to stdout in the given format (json)",
+					},
+					&cli.BoolFlag{
+						Name:  "poll",
+						Usage: "poll for changes instead of using filesystem notifications, used automatically on network and FUSE filesystems",
+					},
+					&cli.DurationFlag{
+						Name:  "poll-interval",
+						Usage: "time between two scans when polling",
+						Value: entr.DefaultPollInterval,
+					},
+					&cli.BoolFlag{
+						Name:  "poll-hash",
+						Usage: "compare file contents by hash when polling instead of modification time and size",
+					},
+					&cli.PathFlag{
+						Name:      "config",
+						Aliases:   []string{"c"},
+						Usage:     "run the tasks declared in a yaml config file",
+						TakesFile: true,
+					},
 				},
-				UsageText: "entr [global options] command args",
+				UsageText: "entr [global options] command args\n" +
+					"entr --events json [--debounce 100ms]\n" +
+					"entr --config watch.yaml\n\n" +
+					"With --events json one JSONL record is written per event, or per batch of\n" +
+					"events if --debounce is set.\n\n" +
+					"A config declares named tasks, tasks without dependencies run once on start:\n" +
+					"  tasks:\n" +
+					"    codegen:\n" +
+					"      recursive: [api]\n" +
+					"      globs: [\"*.proto\"]\n" +
+					"      debounce: 200ms\n" +
+					"      command: make codegen\n" +
+					"    build:\n" +
+					"      recursive: [cmd, internal]\n" +
+					"      command: go build ./...\n" +
+					"      restart: restart # or queue (default), ignore\n" +
+					"      after: [codegen]",
 				Action: func(c *cli.Context) error {
-					var err error
-					events := make(chan notify.EventInfo, 10)
-					if c.String("this") != "" {
-						for _, dir := range c.StringSlice("dir") {
-							err = notify.Watch(dir, events, notify.All)
-							if err != nil {
-								return fmt.Errorf("failed to add dir to watcher: %w", err)
-							}
-						}
-						for _, dir := range c.StringSlice("path") {
-							logger.Info("watching path: %s\n", dir)
-							err = notify.Watch(dir, events, notify.All)
-							err = errors.New("recursive not implemented")
-							if err != nil {
-								return fmt.Errorf("failed to add path to watcher: %w", err)
-							}
+					watchOptions := entr.WatchOptions{
+						Poll:         c.Bool("poll"),
+						PollInterval: c.Duration("poll-interval"),
+						PollHash:     c.Bool("poll-hash"),
+					}
+					if c.String("config") != "" {
+						if c.Args().Len() != 0 {
+							return errors.New("no command may be given with --config")
 						}
-					} else {
-						workingDir, err := os.Getwd()
+						config, err := entr.LoadConfig(c.String("config"))
 						if err != nil {
-							return fmt.Errorf("failed to get working dir: %w", err)
+							return err
 						}
-						logger.Info("watching working dir (%s)\n", workingDir)
-						err = notify.Watch(workingDir, events, notify.All)
-						if err != nil {
-							return fmt.Errorf("failed to add dir to watcher: %w", err)
+						color := os.Getenv("NO_COLOR") == ""
+						if stat, err := os.Stdout.Stat(); err != nil || stat.Mode()&os.ModeCharDevice == 0 {
+							color = false
 						}
+						ctx, stop := signal.NotifyContext(c.Context, os.Interrupt)
+						defer stop()
+						err = entr.NewRunner(config, watchOptions, os.Stdout, os.Stderr, color, logger).Run(ctx)
+						if errors.Is(err, context.Canceled) {
+							return nil
+						}
+						return err
+					}
+					workingDir, err := os.Getwd()
+					if err != nil {
+						return fmt.Errorf("failed to get working dir: %w", err)
+					}
+					if c.IsSet("this") {
+						logger.Warn("--this is deprecated and has no effect")
+					}
+					paths := c.StringSlice("path")
+					recursive := c.StringSlice("recursive")
+					if len(paths) == 0 && len(recursive) == 0 {
+						paths = []string{workingDir}
 					}
 					command := c.Args().Slice()
-					for {
-						select {
-						case event, ok := <-events:
-							if !ok {
-								return errors.New("watcher closed")
+					switch c.String("events") {
+					case "":
+						if len(command) == 0 {
+							return errors.New("no command given")
+						}
+					case "json":
+						if len(command) != 0 {
+							return errors.New("no command may be given with --events")
+						}
+					default:
+						return fmt.Errorf("invalid events format: %s", c.String("events"))
+					}
+					logger.Info("watching", "paths", paths, "recursive", recursive)
+					watcher, err := entr.Watch(paths, recursive, watchOptions)
+					if err != nil {
+						return fmt.Errorf("failed to start watcher: %w", err)
+					}
+					defer watcher.Close()
+					encoder := entr.NewEncoder(os.Stdout, workingDir)
+					for batch := range entr.Batch(watcher.Events(), c.Duration("debounce")) {
+						for _, event := range batch {
+							logger.Debug("new event", "path", event.Path, "event", event.Op)
+						}
+						if c.String("events") == "json" {
+							if c.Duration("debounce") > 0 {
+								err = encoder.WriteBatch(batch)
+							} else {
+								err = encoder.WriteEvent(batch[0])
 							}
-							// TODO implement cooldown period?
-							logger.Info("new event", "path", event.Path(), "event", event.Event().String())
-							err := exec.Command(command[0], command[1:]...).Run()
 							if err != nil {
-								return err
+								return fmt.Errorf("failed to write event: %w", err)
 							}
+							continue
+						}
+						cmd := exec.Command(command[0], command[1:]...)
+						cmd.Stdout = os.Stdout
+						cmd.Stderr = os.Stderr
+						err := cmd.Run()
+						if err != nil {
+							return err
 						}
 					}
+					return errors.New("watcher closed")
 				},
 			},
 			{
@@ -199,140 +288,10 @@ func main() {
 					},
 				},
 			},
-			{
-				Name:    "quick",
-				Aliases: []string{"q"},
-				Flags: []cli.Flag{
-					&cli.PathFlag{
-						Name:      "file",
-						Aliases:   []string{"f"},
-						Usage:     "file to read from",
-						TakesFile: true,
-					},
-				},
-				Usage: "quick-commands, small ui to quickly execute some commands\n" +
-					"reads json config from stdin (or file) with the keys as command" +
-					" names and the values as a tuple of description and command",
-				Action: func(c *cli.Context) error {
-					var all []byte
-					var err error
-					if c.String("file") != "" {
-						all, err = os.ReadFile(c.String("file"))
-						if err != nil {
-							return fmt.Errorf("failed to read file: %w", err)
-						}
-					} else {
-						all, err = io.ReadAll(os.Stdin)
-						if err != nil {
-							return fmt.Errorf("failed to read stdin: %w", err)
-						}
-					}
-					var quickConfig map[string]quickCommand
-					err = json.Unmarshal(all, &quickConfig)
-					if err != nil {
-						return fmt.Errorf("failed to unmarshal json: %w", err)
-					}
-					commandKeys := keys(quickConfig)
-					selected, err := fuzzyfinder.Find(commandKeys, func(i int) string {
-						return commandKeys[i]
-					}, fuzzyfinder.WithPreviewWindow(func(i, w, h int) string {
-						return quickConfig[commandKeys[i]].Description
-					}))
-					if err != nil {
-						if err == fuzzyfinder.ErrAbort {
-							return nil
-						}
-						return fmt.Errorf("failed to find command: %w", err)
-					}
-					quickCommand := quickConfig[commandKeys[selected]]
-					file, _ := syntax.NewParser().Parse(strings.NewReader(quickCommand.Command), commandKeys[selected])
-					runner, _ := interp.New(
-						interp.Env(expand.ListEnviron(os.Environ()...)),
-						interp.StdIO(nil, os.Stdout, os.Stdout),
-					)
-					err = runner.Run(context.TODO(), file)
-					if err != nil {
-						return fmt.Errorf("failed to run command: %w", err)
-					}
-					return nil
-				},
-			},
-			{
-				Name:    "pass",
-				Aliases: []string{"p"},
-				Usage:   "password manager",
-				Subcommands: []*cli.Command{
-					{
-						Name: "decrypt",
-						Flags: []cli.Flag{
-							&cli.PathFlag{
-								Name:      "file",
-								Aliases:   []string{"f"},
-								Usage:     "file to decrypt",
-								TakesFile: true,
-								Required:  true,
-							},
-						},
-						Usage: "decrypt file",
-						Action: func(c *cli.Context) error {
-							file, err := os.Open(c.String("file"))
-							if err != nil {
-								return err
-							}
-							defer func(file *os.File) {
-								err := file.Close()
-								if err != nil {
-									logger.Info("failed to close file: %v+", err)
-								}
-							}(file)
-							idReader := strings.NewReader("AGE-PLUGIN-YUBIKEY-1JAAZ6QVZ0RQLA0GD5ZEPL\n" +
-								"AGE-PLUGIN-YUBIKEY-1ZJRRUQVZE9DJFCC3UPNJD\n" +
-								"AGE-PLUGIN-YUBIKEY-1ZWRRUQVZAJX2Q4C5LJRTD") // TODO load identities from file
-							identities, err := age.ParseIdentities(idReader)
-							if err != nil {
-								return err
-							}
-							decrypt, err := age.Decrypt(file, identities...)
-							if err != nil {
-								return err
-							}
-							all, err := io.ReadAll(decrypt)
-							if err != nil {
-								return err
-							}
-							_, err = os.Stdout.Write(all)
-							if err != nil {
-								return err
-							}
-							return nil
-						},
-					},
-					{
-						Name:    "ui",
-						Aliases: []string{"u"},
-						Flags: []cli.Flag{
-							&cli.BoolFlag{
-								Name:    "fuzzy",
-								Aliases: []string{"f"},
-								Usage:   "fuzzy UI", // NOTE this does nothing for now
-								Value:   true,
-							},
-						},
-						Usage: "start small fuzzy UI mostly to copy passwords out",
-						Action: func(c *cli.Context) error {
-							slice := []string{"one", "two", "three", os.Getenv("EDITOR")}
-							find, err := fuzzyfinder.Find(slice, func(i int) string {
-								return slice[i]
-							})
-							if err != nil {
-								return err
-							}
-							fmt.Println(find)
-							return errors.New("not implemented")
-						},
-					},
-				},
-			},
+			quickCommand(),
+			passCommand(),
+			cryptCommand(),
+			gitCommand(),
 			{
 				Name:    "util",
 				Aliases: []string{"u"},
@@ -383,54 +342,75 @@ func main() {
 							return nil
 						},
 					},
+					spongeCommand(),
 					{
-						Name:    "sponge",
-						Aliases: []string{"s"},
-						Usage:   "soak all input from stdin and write to file/stdin",
+						Name:    "ts",
+						Aliases: []string{"t"},
+						Usage:   "add timestamps to lines from stdin",
+						Description: "Prefixes every line with the time it was read, formatted by the strftime format\n" +
+							"(default \"" + ts.DefaultFormat + "\", or \"" + ts.DefaultElapsedFormat + "\" with -i and -s). %.S, %.s and %.T add microseconds.\n" +
+							"With -r timestamps already in the lines are replaced with how long ago they were,\n" +
+							"or reformatted if a format is given.",
+						ArgsUsage: "[format]",
 						Flags: []cli.Flag{
-							&cli.StringFlag{
-								Name:    "file",
-								Aliases: []string{"f"},
-								Usage:   "file to write to",
+							&cli.BoolFlag{
+								Name:    "incremental",
+								Aliases: []string{"i"},
+								Usage:   "print the time since the previous line",
+							},
+							&cli.BoolFlag{
+								Name:    "since-start",
+								Aliases: []string{"s"},
+								Usage:   "print the time since the start",
+							},
+							&cli.BoolFlag{
+								Name:    "monotonic",
+								Aliases: []string{"m"},
+								Usage:   "use the monotonic clock, unaffected by changes of the system time",
+							},
+							&cli.BoolFlag{
+								Name:    "relative",
+								Aliases: []string{"r"},
+								Usage:   "convert existing timestamps to relative times",
+							},
+							&cli.BoolFlag{
+								Name:    "json",
+								Aliases: []string{"j"},
+								Usage:   "write lines as JSON objects with ts and line",
 							},
 						},
 						Action: func(c *cli.Context) error {
-							all, err := io.ReadAll(os.Stdin)
-							if err != nil {
-								return err
+							if c.Args().Len() > 1 {
+								return errors.New("expected at most one format")
 							}
-							if c.String("file") != "" {
-								err = os.WriteFile(c.String("file"), all, 0644)
-							} else {
-								_, err = os.Stdout.Write(all)
+							opts := ts.Options{
+								Format:    c.Args().First(),
+								Monotonic: c.Bool("monotonic"),
+								JSON:      c.Bool("json"),
 							}
-							return err
-						},
-					},
-					{
-						Name:    "ts",
-						Aliases: []string{"t"},
-						Usage:   "add timestamps to lines from stdin",
-						Action: func(c *cli.Context) error {
-							// TODO
-							return errors.New("not implemented")
+							switch {
+							case c.Bool("incremental") && c.Bool("since-start"), c.Bool("relative") && (c.Bool("incremental") || c.Bool("since-start")):
+								return errors.New("only one of -i, -s and -r can be given")
+							case c.Bool("incremental"):
+								opts.Mode = ts.ModeIncremental
+							case c.Bool("since-start"):
+								opts.Mode = ts.ModeSinceStart
+							case c.Bool("relative"):
+								opts.Mode = ts.ModeRelative
+							}
+							return ts.Run(os.Stdin, os.Stdout, opts)
 						},
 					},
+					chronicCommand(),
+					ifneCommand(),
+					combineCommand(),
+					vidirCommand(),
+					eachCommand(),
+					encodeCommand(),
+					decodeCommand(),
 					// TODO add following commands:
-					// - base64
-					// - ifne
-					// - git-root
-					// - git-skm
-					// - git interactive sparse clone
-					// - git interactive sparse checkout
-					// - combine
-					// - chronic
-					// - sponge
 					// - gron and other json processing tools
 					// - ssh-proxy (for huproxy)
-					// - ts
-					// - xargs like
-					// - vidir
 				},
 			},
 		},
@@ -441,16 +421,6 @@ func main() {
 	}
 }
 
-func keys(m map[string]quickCommand) []string {
-	keys := make([]string, len(m))
-	i := 0
-	for k := range m {
-		keys[i] = k
-		i++
-	}
-	return keys
-}
-
 func parseLogLevel(logLevel string) (slog.Level, error) {
 	switch logLevel {
 	case "debug":
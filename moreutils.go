@@ -0,0 +1,251 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"tasadar.net/tionis/shell-tools/edit"
+	"tasadar.net/tionis/shell-tools/moreutils"
+)
+
+func spongeCommand() *cli.Command {
+	return &cli.Command{
+		Name:    "sponge",
+		Aliases: []string{"s"},
+		Usage:   "soak all input from stdin and write to file/stdin",
+		Description: "The file is only replaced once all input was read, by renaming a temporary file over it,\n" +
+			"keeping its mode, owner and extended attributes. Without a file the input is written to stdout.",
+		ArgsUsage: "[file]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:      "file",
+				Aliases:   []string{"f"},
+				Usage:     "file to write to",
+				TakesFile: true,
+			},
+			&cli.BoolFlag{
+				Name:    "append",
+				Aliases: []string{"a"},
+				Usage:   "append to the file instead of replacing it",
+			},
+			&cli.StringFlag{
+				Name:    "backup",
+				Aliases: []string{"b"},
+				Usage:   "keep the previous file with this suffix, e.g. .bak",
+			},
+			&cli.BoolFlag{
+				Name:  "force",
+				Usage: "write the file even if the result is empty",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			path := c.String("file")
+			if path == "" {
+				path = c.Args().First()
+			}
+			if c.Args().Len() > 1 || c.String("file") != "" && c.Args().Len() > 0 {
+				return errors.New("expected a single file")
+			}
+			if path == "" {
+				buffer := moreutils.NewBuffer(moreutils.DefaultBufferLimit)
+				defer buffer.Close()
+				_, err := io.Copy(buffer, os.Stdin)
+				if err != nil {
+					return fmt.Errorf("failed to read input: %w", err)
+				}
+				_, err = buffer.WriteTo(os.Stdout)
+				return err
+			}
+			err := moreutils.Sponge(os.Stdin, path, moreutils.SpongeOptions{
+				Append: c.Bool("append"),
+				Backup: c.String("backup"),
+				Force:  c.Bool("force"),
+			})
+			if errors.Is(err, moreutils.ErrEmptyResult) {
+				return fmt.Errorf("%w, use --force to write it anyway", err)
+			}
+			return err
+		},
+	}
+}
+
+func chronicCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "chronic",
+		Usage:     "run a command quietly, showing its output only if it fails",
+		ArgsUsage: "command [args...]",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:    "stderr",
+				Aliases: []string{"e"},
+				Usage:   "also show the output if the command wrote to stderr",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() == 0 {
+				return errors.New("no command given")
+			}
+			cmd := exec.Command(c.Args().First(), c.Args().Tail()...)
+			cmd.Stdin = os.Stdin
+			return commandExit(moreutils.Chronic(cmd, os.Stdout, os.Stderr, c.Bool("stderr")))
+		},
+	}
+}
+
+func ifneCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "ifne",
+		Usage:     "run a command if stdin is not empty",
+		ArgsUsage: "command [args...]",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:    "empty",
+				Aliases: []string{"n"},
+				Usage:   "run the command if stdin is empty, otherwise pass stdin through",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() == 0 {
+				return errors.New("no command given")
+			}
+			return moreutils.Ifne(os.Stdin, os.Stdout, c.Bool("empty"), func(stdin io.Reader) error {
+				cmd := exec.Command(c.Args().First(), c.Args().Tail()...)
+				cmd.Stdin = stdin
+				cmd.Stdout = os.Stdout
+				cmd.Stderr = os.Stderr
+				return commandExit(cmd.Run())
+			})
+		},
+	}
+}
+
+func combineCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "combine",
+		Usage: "combine the lines of two files with a set operation",
+		Description: "and: lines of file1 also in file2, not: lines of file1 not in file2,\n" +
+			"or: lines of both files, xor: lines of either file not in the other.\n" +
+			"Either file can be - for stdin.",
+		ArgsUsage: "file1 and|or|not|xor file2",
+		Action: func(c *cli.Context) error {
+			if c.Args().Len() != 3 {
+				return errors.New("expected file1, an operation and file2")
+			}
+			file1, op, file2 := c.Args().Get(0), c.Args().Get(1), c.Args().Get(2)
+			if file1 == "-" && file2 == "-" {
+				return errors.New("only one file can be read from stdin")
+			}
+			var readers []io.Reader
+			for _, path := range []string{file1, file2} {
+				if path == "-" {
+					readers = append(readers, os.Stdin)
+					continue
+				}
+				file, err := os.Open(path)
+				if err != nil {
+					return fmt.Errorf("failed to open file: %w", err)
+				}
+				defer file.Close()
+				readers = append(readers, file)
+			}
+			return moreutils.Combine(op, readers[0], readers[1], os.Stdout)
+		},
+	}
+}
+
+func vidirCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "vidir",
+		Usage: "rename, move and delete files by editing a listing in $EDITOR",
+		Description: "Lists the entries of the given directories and the given files, or the paths read from stdin,\n" +
+			"or else the current directory. Change a path to rename or move it, missing directories are\n" +
+			"created. Remove a line to delete its file or empty directory.",
+		ArgsUsage: "[paths...]",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:    "dry-run",
+				Aliases: []string{"n"},
+				Usage:   "only print the changes",
+			},
+			&cli.BoolFlag{
+				Name:    "yes",
+				Aliases: []string{"y"},
+				Usage:   "apply the changes without asking for confirmation",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			paths := c.Args().Slice()
+			if len(paths) == 0 && !isTerminal(os.Stdin) {
+				data, err := io.ReadAll(os.Stdin)
+				if err != nil {
+					return fmt.Errorf("failed to read paths: %w", err)
+				}
+				for _, line := range strings.Split(string(data), "\n") {
+					if line != "" {
+						paths = append(paths, line)
+					}
+				}
+				// The editor needs the terminal instead of the consumed stdin.
+				tty, err := openTTY()
+				if err != nil {
+					return err
+				}
+				os.Stdin = tty
+			}
+			if len(paths) == 0 {
+				paths = []string{"."}
+			}
+			listing, err := moreutils.Listing(paths)
+			if err != nil {
+				return err
+			}
+			if len(listing) == 0 {
+				return errors.New("nothing to edit")
+			}
+			edited, changed, err := edit.Content("vidir", []byte(moreutils.FormatListing(listing)))
+			if err != nil {
+				return err
+			}
+			if !changed {
+				return nil
+			}
+			ops, err := moreutils.ParseListing(listing, string(edited))
+			if err != nil {
+				return err
+			}
+			if len(ops) == 0 {
+				return nil
+			}
+			err = moreutils.Check(ops)
+			if err != nil {
+				return err
+			}
+			for _, op := range ops {
+				fmt.Fprintln(os.Stderr, op)
+			}
+			if c.Bool("dry-run") {
+				return nil
+			}
+			if !c.Bool("yes") {
+				ok, err := confirm(fmt.Sprintf("Apply %d changes?", len(ops)))
+				if err != nil || !ok {
+					return err
+				}
+			}
+			return moreutils.Apply(ops)
+		},
+	}
+}
+
+// commandExit passes the exit code of a failed command on.
+func commandExit(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return cli.Exit("", exitErr.ExitCode())
+	}
+	return err
+}
@@ -0,0 +1,85 @@
+// Package moreutils implements shell tools in the spirit of moreutils.
+package moreutils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// DefaultBufferLimit is the number of bytes a [Buffer] keeps in memory
+// before spilling to a temporary file.
+const DefaultBufferLimit = 1 << 20
+
+// Buffer collects written data in memory up to a limit and in a temporary
+// file beyond it, so large outputs do not have to fit into memory.
+// It must be closed to remove the temporary file.
+type Buffer struct {
+	limit int
+	mem   bytes.Buffer
+	file  *os.File
+	size  int64
+}
+
+// NewBuffer returns a buffer keeping up to limit bytes in memory.
+func NewBuffer(limit int) *Buffer {
+	return &Buffer{limit: limit}
+}
+
+// Write appends p to the buffer.
+func (b *Buffer) Write(p []byte) (int, error) {
+	if b.file == nil && b.mem.Len()+len(p) > b.limit {
+		file, err := os.CreateTemp("", "shell-tools-buffer-*")
+		if err != nil {
+			return 0, fmt.Errorf("failed to create buffer file: %w", err)
+		}
+		b.file = file
+		_, err = b.mem.WriteTo(file)
+		if err != nil {
+			return 0, fmt.Errorf("failed to write buffer file: %w", err)
+		}
+	}
+	var n int
+	var err error
+	if b.file != nil {
+		n, err = b.file.Write(p)
+	} else {
+		n, err = b.mem.Write(p)
+	}
+	b.size += int64(n)
+	return n, err
+}
+
+// Len returns the number of bytes written to the buffer.
+func (b *Buffer) Len() int64 {
+	return b.size
+}
+
+// Reader returns a reader of the buffered data from its start.
+// Writes to the buffer must not happen while it is read.
+func (b *Buffer) Reader() io.Reader {
+	if b.file == nil {
+		return bytes.NewReader(b.mem.Bytes())
+	}
+	return io.NewSectionReader(b.file, 0, b.size)
+}
+
+// WriteTo writes the buffered data to w.
+func (b *Buffer) WriteTo(w io.Writer) (int64, error) {
+	return io.Copy(w, b.Reader())
+}
+
+// Close removes the temporary file of the buffer, if any.
+func (b *Buffer) Close() error {
+	if b.file == nil {
+		return nil
+	}
+	closeErr := b.file.Close()
+	err := os.Remove(b.file.Name())
+	b.file = nil
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
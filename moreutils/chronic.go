@@ -0,0 +1,37 @@
+package moreutils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+)
+
+// Chronic runs cmd with its output buffered and only writes the output to
+// stdout and stderr if it fails, or with triggerOnStderr also if it wrote to
+// stderr. The error of cmd is returned, so its exit code can be passed on.
+func Chronic(cmd *exec.Cmd, stdout, stderr io.Writer, triggerOnStderr bool) error {
+	outBuffer := NewBuffer(DefaultBufferLimit)
+	defer outBuffer.Close()
+	errBuffer := NewBuffer(DefaultBufferLimit)
+	defer errBuffer.Close()
+	cmd.Stdout = outBuffer
+	cmd.Stderr = errBuffer
+	runErr := cmd.Run()
+	var exitErr *exec.ExitError
+	if runErr != nil && !errors.As(runErr, &exitErr) {
+		return fmt.Errorf("failed to run command: %w", runErr)
+	}
+	if runErr == nil && !(triggerOnStderr && errBuffer.Len() > 0) {
+		return nil
+	}
+	_, err := outBuffer.WriteTo(stdout)
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	_, err = errBuffer.WriteTo(stderr)
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return runErr
+}
@@ -0,0 +1,59 @@
+package moreutils
+
+import (
+	"bytes"
+	"io"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChronic(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := Chronic(exec.Command("sh", "-c", "echo out; echo err >&2"), &stdout, &stderr, false)
+	require.NoError(t, err)
+	assert.Empty(t, stdout.String())
+	assert.Empty(t, stderr.String())
+
+	err = Chronic(exec.Command("sh", "-c", "echo out; echo err >&2"), &stdout, &stderr, true)
+	require.NoError(t, err)
+	assert.Equal(t, "out\n", stdout.String())
+	assert.Equal(t, "err\n", stderr.String())
+
+	stdout.Reset()
+	stderr.Reset()
+	err = Chronic(exec.Command("sh", "-c", "echo failed; exit 3"), &stdout, &stderr, false)
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 3, exitErr.ExitCode())
+	assert.Equal(t, "failed\n", stdout.String())
+}
+
+func TestIfne(t *testing.T) {
+	for _, test := range []struct {
+		input    string
+		invert   bool
+		ran      bool
+		expected string
+	}{
+		{"data\n", false, true, "ran with data\n"},
+		{"", false, false, ""},
+		{"", true, true, "ran with \n"},
+		{"data\n", true, false, "data\n"},
+	} {
+		var out bytes.Buffer
+		ran := false
+		err := Ifne(strings.NewReader(test.input), &out, test.invert, func(stdin io.Reader) error {
+			ran = true
+			data, err := io.ReadAll(stdin)
+			out.WriteString("ran with " + strings.TrimSpace(string(data)) + "\n")
+			return err
+		})
+		require.NoError(t, err)
+		assert.Equal(t, test.ran, ran, test.input)
+		assert.Equal(t, test.expected, out.String(), test.input)
+	}
+}
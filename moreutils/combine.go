@@ -0,0 +1,142 @@
+package moreutils
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Operations of [Combine].
+const (
+	CombineAnd = "and"
+	CombineOr  = "or"
+	CombineNot = "not"
+	CombineXor = "xor"
+)
+
+// lineSet holds hashes of lines, so memory grows with the number of distinct
+// lines but not with their length.
+type lineSet map[[sha256.Size]byte]struct{}
+
+func (s lineSet) add(line string) {
+	s[sha256.Sum256([]byte(line))] = struct{}{}
+}
+
+func (s lineSet) contains(line string) bool {
+	_, ok := s[sha256.Sum256([]byte(line))]
+	return ok
+}
+
+// Combine writes the lines of file1 and file2 combined by op to w:
+// "and" writes the lines of file1 also in file2, "not" those not in file2,
+// "or" the lines of both files and "xor" the lines of either file not in
+// the other. Lines are written in input order, keeping duplicates.
+func Combine(op string, file1, file2 io.Reader, w io.Writer) error {
+	writer := bufio.NewWriter(w)
+	switch op {
+	case CombineOr:
+		for _, r := range []io.Reader{file1, file2} {
+			err := eachLine(r, func(line string) error {
+				return writeLine(writer, line)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	case CombineAnd, CombineNot:
+		set, err := readLineSet(file2, nil)
+		if err != nil {
+			return err
+		}
+		err = eachLine(file1, func(line string) error {
+			if set.contains(line) != (op == CombineAnd) {
+				return nil
+			}
+			return writeLine(writer, line)
+		})
+		if err != nil {
+			return err
+		}
+	case CombineXor:
+		// file2 has to be read twice, once for its set of lines and once
+		// for the lines not in file1, so it is kept in a buffer.
+		buffer := NewBuffer(DefaultBufferLimit)
+		defer buffer.Close()
+		set2, err := readLineSet(file2, buffer)
+		if err != nil {
+			return err
+		}
+		set1 := lineSet{}
+		err = eachLine(file1, func(line string) error {
+			set1.add(line)
+			if set2.contains(line) {
+				return nil
+			}
+			return writeLine(writer, line)
+		})
+		if err != nil {
+			return err
+		}
+		err = eachLine(buffer.Reader(), func(line string) error {
+			if set1.contains(line) {
+				return nil
+			}
+			return writeLine(writer, line)
+		})
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown operation %q, expected and, or, not or xor", op)
+	}
+	err := writer.Flush()
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// readLineSet returns the set of lines of r, copying r to copyTo if it is
+// not nil.
+func readLineSet(r io.Reader, copyTo io.Writer) (lineSet, error) {
+	if copyTo != nil {
+		r = io.TeeReader(r, copyTo)
+	}
+	set := lineSet{}
+	err := eachLine(r, func(line string) error {
+		set.add(line)
+		return nil
+	})
+	return set, err
+}
+
+// eachLine calls fn with every line of r without its line ending.
+func eachLine(r io.Reader, fn func(line string) error) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			fnErr := fn(strings.TrimSuffix(line, "\n"))
+			if fnErr != nil {
+				return fnErr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read line: %w", err)
+		}
+	}
+}
+
+func writeLine(w *bufio.Writer, line string) error {
+	_, err := w.WriteString(line + "\n")
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package moreutils
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCombine(t *testing.T) {
+	file1 := "a\nb\nc\nb\n"
+	file2 := "b\nd\na"
+	for op, expected := range map[string]string{
+		CombineAnd: "a\nb\nb\n",
+		CombineNot: "c\n",
+		CombineOr:  "a\nb\nc\nb\nb\nd\na\n",
+		CombineXor: "c\nd\n",
+	} {
+		var out bytes.Buffer
+		require.NoError(t, Combine(op, strings.NewReader(file1), strings.NewReader(file2), &out), op)
+		assert.Equal(t, expected, out.String(), op)
+	}
+	err := Combine("nand", strings.NewReader(file1), strings.NewReader(file2), &bytes.Buffer{})
+	assert.ErrorContains(t, err, "unknown operation")
+}
+
+func TestBufferSpills(t *testing.T) {
+	buffer := NewBuffer(4)
+	_, err := buffer.Write([]byte("abc"))
+	require.NoError(t, err)
+	assert.Nil(t, buffer.file)
+	_, err = buffer.Write([]byte("defg"))
+	require.NoError(t, err)
+	require.NotNil(t, buffer.file)
+	name := buffer.file.Name()
+	assert.Equal(t, int64(7), buffer.Len())
+	var out bytes.Buffer
+	_, err = buffer.WriteTo(&out)
+	require.NoError(t, err)
+	assert.Equal(t, "abcdefg", out.String())
+	require.NoError(t, buffer.Close())
+	assert.NoFileExists(t, name)
+}
@@ -0,0 +1,30 @@
+package moreutils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Ifne calls run with stdin only if stdin is not empty. If invert is set,
+// run is only called if stdin is empty, otherwise stdin is copied to stdout.
+// Stdin is streamed, not read into memory.
+func Ifne(stdin io.Reader, stdout io.Writer, invert bool, run func(stdin io.Reader) error) error {
+	reader := bufio.NewReader(stdin)
+	_, err := reader.Peek(1)
+	empty := errors.Is(err, io.EOF)
+	if err != nil && !empty {
+		return fmt.Errorf("failed to read stdin: %w", err)
+	}
+	switch {
+	case empty == invert:
+		return run(reader)
+	case invert:
+		_, err = io.Copy(stdout, reader)
+		if err != nil {
+			return fmt.Errorf("failed to copy stdin: %w", err)
+		}
+	}
+	return nil
+}
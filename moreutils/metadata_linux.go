@@ -0,0 +1,37 @@
+package moreutils
+
+import (
+	"os"
+	"strings"
+	"syscall"
+)
+
+// preserveMetadata copies the owner and extended attributes of the file at
+// path, described by info, to dst. Failures are ignored, as an unprivileged
+// user can not give files away or set every attribute.
+func preserveMetadata(info os.FileInfo, path string, dst *os.File) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		_ = dst.Chown(int(stat.Uid), int(stat.Gid))
+	}
+	size, err := syscall.Listxattr(path, nil)
+	if err != nil || size == 0 {
+		return
+	}
+	names := make([]byte, size)
+	size, err = syscall.Listxattr(path, names)
+	if err != nil {
+		return
+	}
+	for _, name := range strings.Split(strings.TrimRight(string(names[:size]), "\x00"), "\x00") {
+		size, err := syscall.Getxattr(path, name, nil)
+		if err != nil {
+			continue
+		}
+		value := make([]byte, size)
+		size, err = syscall.Getxattr(path, name, value)
+		if err != nil {
+			continue
+		}
+		_ = syscall.Setxattr(dst.Name(), name, value[:size], 0)
+	}
+}
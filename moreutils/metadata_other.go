@@ -0,0 +1,7 @@
+//go:build !linux
+
+package moreutils
+
+import "os"
+
+func preserveMetadata(_ os.FileInfo, _ string, _ *os.File) {}
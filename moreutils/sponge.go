@@ -0,0 +1,151 @@
+package moreutils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ErrEmptyResult is returned by [Sponge] instead of writing an empty file.
+var ErrEmptyResult = errors.New("refusing to write an empty result")
+
+// SpongeOptions configure [Sponge].
+type SpongeOptions struct {
+	// Append appends the input to the file instead of replacing it.
+	Append bool
+	// Backup keeps the previous file with this suffix, if it is not empty.
+	Backup string
+	// Force writes the file even if the result is empty.
+	Force bool
+}
+
+// Sponge soaks up all of r and only then replaces the file at path, so the
+// file can be read by the command producing r. The input is collected in a
+// temporary file next to the target, which is renamed over the target once
+// complete, so a crash never leaves a partially written file. Inputs too
+// large for memory are spilled to disk. Mode, owner and extended attributes
+// of the previous file are kept where possible.
+func Sponge(r io.Reader, path string, opts SpongeOptions) error {
+	// Replace the target of a symlink, not the symlink itself.
+	target, err := filepath.EvalSymlinks(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		target = path
+	} else if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	info, err := os.Stat(target)
+	exists := err == nil
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	if exists && !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", path)
+	}
+
+	input := NewBuffer(DefaultBufferLimit)
+	defer input.Close()
+	_, err = io.Copy(input, r)
+	if err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".sponge-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	var size int64
+	if opts.Append && exists {
+		size, err = copyFile(tmp, target)
+		if err != nil {
+			return err
+		}
+	}
+	n, err := input.WriteTo(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if size+n == 0 && !opts.Force {
+		return ErrEmptyResult
+	}
+
+	mode := fs.FileMode(0o644)
+	if exists {
+		mode = info.Mode().Perm()
+		preserveMetadata(info, target, tmp)
+	}
+	err = tmp.Chmod(mode)
+	if err != nil {
+		return fmt.Errorf("failed to set mode: %w", err)
+	}
+	err = tmp.Sync()
+	if err != nil {
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+	err = tmp.Close()
+	if err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if exists && opts.Backup != "" {
+		err = backupFile(target, target+opts.Backup)
+		if err != nil {
+			return err
+		}
+	}
+	err = os.Rename(tmp.Name(), target)
+	if err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
+
+// copyFile writes the contents of the file at path to w.
+func copyFile(w io.Writer, path string) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+	n, err := io.Copy(w, file)
+	if err != nil {
+		return n, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return n, nil
+}
+
+// backupFile makes backup a copy of the file at path, as a hard link if
+// possible.
+func backupFile(path, backup string) error {
+	err := os.Remove(backup)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove old backup: %w", err)
+	}
+	if os.Link(path, backup) == nil {
+		return nil
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	dst, err := os.OpenFile(backup, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	_, err = io.Copy(dst, src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	return nil
+}
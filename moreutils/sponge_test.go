@@ -0,0 +1,53 @@
+package moreutils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSponge(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	require.NoError(t, os.WriteFile(path, []byte("old\n"), 0o600))
+	link := filepath.Join(dir, "link")
+	require.NoError(t, os.Symlink("config", link))
+
+	require.NoError(t, Sponge(strings.NewReader("new\n"), link, SpongeOptions{Append: true, Backup: "~"}))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "old\nnew\n", string(data))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	backup, err := os.ReadFile(path + "~")
+	require.NoError(t, err)
+	assert.Equal(t, "old\n", string(backup))
+	target, err := os.Readlink(link)
+	require.NoError(t, err)
+	assert.Equal(t, "config", target, "the symlink is kept")
+
+	err = Sponge(strings.NewReader(""), path, SpongeOptions{})
+	assert.ErrorIs(t, err, ErrEmptyResult)
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "old\nnew\n", string(data))
+	require.NoError(t, Sponge(strings.NewReader(""), path, SpongeOptions{Force: true}))
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Empty(t, data)
+
+	large := strings.Repeat("x", DefaultBufferLimit+1)
+	require.NoError(t, Sponge(strings.NewReader(large), filepath.Join(dir, "new"), SpongeOptions{}))
+	data, err = os.ReadFile(filepath.Join(dir, "new"))
+	require.NoError(t, err)
+	assert.Equal(t, large, string(data))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 4, "no temp files are left behind")
+}
@@ -0,0 +1,228 @@
+package moreutils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kinds of [Operation].
+const (
+	OpRename = "rename"
+	OpDelete = "delete"
+)
+
+// Operation is a change to a path made by editing a listing.
+type Operation struct {
+	Kind string
+	From string
+	// To is the new path of a rename.
+	To string
+}
+
+func (o Operation) String() string {
+	if o.Kind == OpDelete {
+		return "delete " + o.From
+	}
+	return "rename " + o.From + " -> " + o.To
+}
+
+// Listing returns the entries of the directories in paths and the other
+// paths themselves, in the order given and sorted within directories.
+func Listing(paths []string) ([]string, error) {
+	var listing []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		if !info.IsDir() {
+			listing = append(listing, path)
+			continue
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read dir %s: %w", path, err)
+		}
+		for _, entry := range entries {
+			if path == "." {
+				listing = append(listing, entry.Name())
+			} else {
+				listing = append(listing, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+	return listing, nil
+}
+
+// FormatListing numbers the paths of listing, one per line.
+func FormatListing(listing []string) string {
+	var b strings.Builder
+	for i, path := range listing {
+		b.WriteString(strconv.Itoa(i+1) + "\t" + path + "\n")
+	}
+	return b.String()
+}
+
+// ParseListing compares the edited listing with the original one and returns
+// the operations to apply: renames for changed paths, and deletions for
+// numbers removed from the listing.
+func ParseListing(listing []string, edited string) ([]Operation, error) {
+	seen := make([]bool, len(listing))
+	var ops []Operation
+	scanner := bufio.NewScanner(strings.NewReader(edited))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		number, path, ok := strings.Cut(line, "\t")
+		if !ok {
+			number, path, ok = strings.Cut(line, " ")
+		}
+		n, err := strconv.Atoi(number)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("line %d: expected a number and a path", lineNumber)
+		}
+		if n < 1 || n > len(listing) {
+			return nil, fmt.Errorf("line %d: unknown number %d", lineNumber, n)
+		}
+		if seen[n-1] {
+			return nil, fmt.Errorf("line %d: number %d is used twice", lineNumber, n)
+		}
+		seen[n-1] = true
+		path = strings.TrimSpace(path)
+		if path == "" {
+			return nil, fmt.Errorf("line %d: path is empty", lineNumber)
+		}
+		if filepath.Clean(path) != filepath.Clean(listing[n-1]) {
+			ops = append(ops, Operation{Kind: OpRename, From: listing[n-1], To: path})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for i, path := range listing {
+		if !seen[i] {
+			ops = append(ops, Operation{Kind: OpDelete, From: path})
+		}
+	}
+	return ops, nil
+}
+
+// Check returns an error if ops can not be applied: if a path would be
+// overwritten, renamed twice, or is changed along with a directory it is in,
+// or if a directory to delete is not empty.
+func Check(ops []Operation) error {
+	changed := map[string]bool{}
+	for _, op := range ops {
+		changed[filepath.Clean(op.From)] = true
+	}
+	targets := map[string]string{}
+	for _, op := range ops {
+		from := filepath.Clean(op.From)
+		for dir := filepath.Dir(from); dir != "." && dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+			if changed[dir] {
+				return fmt.Errorf("%s can not be changed along with %s", op.From, dir)
+			}
+		}
+		if op.Kind == OpDelete {
+			entries, err := os.ReadDir(op.From)
+			if err == nil && len(entries) > 0 {
+				return fmt.Errorf("directory %s is not empty", op.From)
+			}
+			continue
+		}
+		to := filepath.Clean(op.To)
+		if other, ok := targets[to]; ok {
+			return fmt.Errorf("%s and %s are both renamed to %s", other, op.From, op.To)
+		}
+		targets[to] = op.From
+		_, err := os.Lstat(to)
+		if err == nil && !changed[to] {
+			return fmt.Errorf("renaming %s would overwrite %s", op.From, op.To)
+		}
+	}
+	return nil
+}
+
+// Apply checks and applies ops. Renamed paths are first moved to temporary
+// names next to them, so swaps and cycles of renames work. Missing parent
+// directories of new paths are created; directories are only deleted if
+// they are empty. If a rename fails, the remaining paths are moved back.
+func Apply(ops []Operation) error {
+	err := Check(ops)
+	if err != nil {
+		return err
+	}
+	var renames []Operation
+	for _, op := range ops {
+		if op.Kind == OpDelete {
+			err = os.Remove(op.From)
+			if err != nil {
+				return fmt.Errorf("failed to delete %s: %w", op.From, err)
+			}
+			continue
+		}
+		renames = append(renames, op)
+	}
+
+	staged := make([]string, len(renames))
+	for i, op := range renames {
+		tmp, err := tempName(op.From)
+		if err == nil {
+			err = os.Rename(op.From, tmp)
+		}
+		if err != nil {
+			for j := 0; j < i; j++ {
+				_ = os.Rename(staged[j], renames[j].From)
+			}
+			return fmt.Errorf("failed to rename %s: %w", op.From, err)
+		}
+		staged[i] = tmp
+	}
+
+	// Create directories before the paths moved into them.
+	order := make([]int, len(renames))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return depth(renames[order[a]].To) < depth(renames[order[b]].To)
+	})
+	for n, i := range order {
+		op := renames[i]
+		err := os.MkdirAll(filepath.Dir(op.To), 0o755)
+		if err == nil {
+			err = os.Rename(staged[i], op.To)
+		}
+		if err != nil {
+			for _, j := range order[n:] {
+				_ = os.Rename(staged[j], renames[j].From)
+			}
+			return fmt.Errorf("failed to rename %s to %s: %w", op.From, op.To, err)
+		}
+	}
+	return nil
+}
+
+// tempName returns an unused name in the directory of path.
+func tempName(path string) (string, error) {
+	for i := 0; i < 100; i++ {
+		name := filepath.Join(filepath.Dir(path), ".vidir-"+strconv.Itoa(os.Getpid())+"-"+strconv.Itoa(i)+"-"+filepath.Base(path))
+		_, err := os.Lstat(name)
+		if errors.Is(err, os.ErrNotExist) {
+			return name, nil
+		}
+	}
+	return "", errors.New("no unused temporary name found")
+}
+
+func depth(path string) int {
+	return strings.Count(filepath.Clean(path), string(filepath.Separator))
+}
@@ -0,0 +1,78 @@
+package moreutils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func readFiles(t *testing.T, dir string) map[string]string {
+	files := map[string]string{}
+	require.NoError(t, filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		rel, _ := filepath.Rel(dir, path)
+		files[rel] = string(data)
+		return err
+	}))
+	return files
+}
+
+func TestVidir(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a": "A", "b": "B", "c": "C", "d": "D", "e": "E", "empty/.keep": ""})
+	require.NoError(t, os.Remove(filepath.Join(dir, "empty", ".keep")))
+	listing, err := Listing([]string{dir})
+	require.NoError(t, err)
+	require.Len(t, listing, 6)
+	assert.Equal(t, "1\t"+filepath.Join(dir, "a")+"\n", FormatListing(listing[:1]))
+
+	join := func(name string) string { return filepath.Join(dir, name) }
+	edited := "1\t" + join("b") + "\n" + // swap a and b
+		"2\t" + join("a") + "\n" +
+		"3 " + join("d") + "\n" + // cycle c -> d -> c
+		"4\t" + join("c") + "\n\n" +
+		"5\t" + join("shots/2023/e.png") + "\n" // move into new directories
+	ops, err := ParseListing(listing, edited)
+	require.NoError(t, err)
+	assert.Equal(t, Operation{Kind: OpDelete, From: join("empty")}, ops[len(ops)-1])
+	require.NoError(t, Apply(ops))
+	assert.Equal(t, map[string]string{"a": "B", "b": "A", "c": "D", "d": "C", "shots/2023/e.png": "E"}, readFiles(t, dir))
+	assert.NoDirExists(t, join("empty"))
+}
+
+func TestVidirErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a": "A", "b": "B", "sub/c": "C"})
+	listing := []string{filepath.Join(dir, "a"), filepath.Join(dir, "b"), filepath.Join(dir, "sub")}
+
+	_, err := ParseListing(listing, "1\tx\n1\ty\n")
+	assert.ErrorContains(t, err, "used twice")
+	_, err = ParseListing(listing, "7\tx\n")
+	assert.ErrorContains(t, err, "unknown number")
+
+	ops, err := ParseListing(listing, "1\t"+filepath.Join(dir, "b")+"\n3\t"+filepath.Join(dir, "sub"))
+	require.NoError(t, err)
+	assert.Equal(t, []Operation{{Kind: OpDelete, From: filepath.Join(dir, "b")}}, ops[1:])
+	assert.NoError(t, Check(ops), "b is deleted before a takes its name")
+
+	ops, err = ParseListing(listing, "1\t"+filepath.Join(dir, "b")+"\n2\t"+filepath.Join(dir, "b")+"2\n")
+	require.NoError(t, err)
+	assert.ErrorContains(t, Apply(ops), "sub is not empty")
+	ops = ops[:1]
+	assert.ErrorContains(t, Apply(ops), "would overwrite")
+	assert.Equal(t, map[string]string{"a": "A", "b": "B", "sub/c": "C"}, readFiles(t, dir))
+}
@@ -0,0 +1,914 @@
+package main
+
+import (
+	"errors"
+	"filippo.io/age"
+	"fmt"
+	"github.com/ktr0731/go-fuzzyfinder"
+	"github.com/urfave/cli/v2"
+	"io"
+	"mvdan.cc/sh/v3/syntax"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"tasadar.net/tionis/shell-tools/edit"
+	"tasadar.net/tionis/shell-tools/pass"
+	"time"
+)
+
+func passCommand() *cli.Command {
+	return &cli.Command{
+		Name:    "pass",
+		Aliases: []string{"p"},
+		Usage:   "password manager",
+		Flags: []cli.Flag{
+			&cli.PathFlag{
+				Name:    "store",
+				Aliases: []string{"s"},
+				Usage:   "password store directory (default: ~/.age-store)",
+				EnvVars: []string{"SHELL_TOOLS_PASS_DIR"},
+			},
+			&cli.PathFlag{
+				Name:      "identities",
+				Aliases:   []string{"i"},
+				Usage:     "age identity file (default: <user config dir>/shell-tools/identities)",
+				EnvVars:   []string{"SHELL_TOOLS_AGE_IDENTITIES"},
+				TakesFile: true,
+			},
+		},
+		Subcommands: []*cli.Command{
+			{
+				Name:      "init",
+				Usage:     "set the recipients of the store or a subdirectory",
+				ArgsUsage: "recipient...",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "path",
+						Aliases: []string{"p"},
+						Usage:   "subdirectory to set the recipients for",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if c.Args().Len() == 0 {
+						return errors.New("no recipients given")
+					}
+					store, err := openStore(c, false)
+					if err != nil {
+						return err
+					}
+					_, err = store.SetRecipients(c.String("path"), c.Args().Slice())
+					return err
+				},
+			},
+			{
+				Name:      "ls",
+				Aliases:   []string{"list"},
+				Usage:     "list entries as a tree",
+				ArgsUsage: "[subdir]",
+				Action: func(c *cli.Context) error {
+					store, err := openStore(c, false)
+					if err != nil {
+						return err
+					}
+					return store.Tree(os.Stdout, c.Args().First())
+				},
+			},
+			{
+				Name:      "show",
+				Usage:     "decrypt and print an entry",
+				ArgsUsage: "name",
+				Action: func(c *cli.Context) error {
+					name := c.Args().First()
+					store, err := openStore(c, name != "")
+					if err != nil {
+						return err
+					}
+					if name == "" || store.IsDir(name) && !store.Exists(name) {
+						return store.Tree(os.Stdout, name)
+					}
+					data, err := store.Decrypt(name)
+					if err != nil {
+						return err
+					}
+					_, err = os.Stdout.Write(data)
+					return err
+				},
+			},
+			{
+				Name:      "insert",
+				Aliases:   []string{"add"},
+				Usage:     "insert a new entry, prompting for it or reading it from stdin",
+				ArgsUsage: "name",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "multiline",
+						Aliases: []string{"m"},
+						Usage:   "read a multiline entry from stdin until EOF",
+					},
+					&cli.BoolFlag{
+						Name:    "force",
+						Aliases: []string{"f"},
+						Usage:   "overwrite an existing entry",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if c.Args().Len() != 1 {
+						return errors.New("invalid number of arguments")
+					}
+					name := c.Args().First()
+					store, err := openStore(c, false)
+					if err != nil {
+						return err
+					}
+					if !c.Bool("force") && store.Exists(name) {
+						return fmt.Errorf("%w: %s", pass.ErrExists, name)
+					}
+					var data []byte
+					if c.Bool("multiline") || !isTerminal(os.Stdin) {
+						data, err = io.ReadAll(os.Stdin)
+						if err != nil {
+							return err
+						}
+					} else {
+						secret, err := readNewSecret("Password for " + name)
+						if err != nil {
+							return err
+						}
+						data = []byte(secret + "\n")
+					}
+					return store.Insert(name, data, c.Bool("force"))
+				},
+			},
+			{
+				Name:      "edit",
+				Usage:     "edit an entry with $EDITOR",
+				ArgsUsage: "name",
+				Action: func(c *cli.Context) error {
+					if c.Args().Len() != 1 {
+						return errors.New("invalid number of arguments")
+					}
+					name := c.Args().First()
+					store, err := openStore(c, true)
+					if err != nil {
+						return err
+					}
+					var data []byte
+					if store.Exists(name) {
+						data, err = store.Decrypt(name)
+						if err != nil {
+							return err
+						}
+					}
+					edited, changed, err := edit.Content(name, data)
+					if err != nil {
+						return err
+					}
+					if !changed {
+						logger.Info("entry unchanged", "name", name)
+						return nil
+					}
+					return store.Encrypt(name, edited)
+				},
+			},
+			{
+				Name:      "rm",
+				Aliases:   []string{"remove"},
+				Usage:     "remove an entry or directory",
+				ArgsUsage: "name",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "recursive",
+						Aliases: []string{"r"},
+						Usage:   "remove directories and their contents",
+					},
+					&cli.BoolFlag{
+						Name:    "force",
+						Aliases: []string{"f"},
+						Usage:   "do not ask for confirmation",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if c.Args().Len() != 1 {
+						return errors.New("invalid number of arguments")
+					}
+					name := c.Args().First()
+					store, err := openStore(c, false)
+					if err != nil {
+						return err
+					}
+					if !c.Bool("force") {
+						ok, err := confirm("Really remove " + name + "?")
+						if err != nil || !ok {
+							return err
+						}
+					}
+					return store.Remove(name, c.Bool("recursive"))
+				},
+			},
+			{
+				Name:      "mv",
+				Aliases:   []string{"move"},
+				Usage:     "move an entry or directory, re-encrypting it for its new location",
+				ArgsUsage: "from to",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "force",
+						Aliases: []string{"f"},
+						Usage:   "overwrite existing entries",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if c.Args().Len() != 2 {
+						return errors.New("invalid number of arguments")
+					}
+					store, err := openStore(c, true)
+					if err != nil {
+						return err
+					}
+					return store.Move(c.Args().Get(0), c.Args().Get(1), c.Bool("force"))
+				},
+			},
+			{
+				Name:      "generate",
+				Aliases:   []string{"gen"},
+				Usage:     "generate a password or passphrase and store it",
+				ArgsUsage: "name [length]",
+				Description: "length is the number of characters, or of words with --passphrase.\n" +
+					"The generated secret is encrypted into the store without being written to disk in plain text.",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "no-symbols",
+						Aliases: []string{"n"},
+						Usage:   "do not use symbols",
+					},
+					&cli.BoolFlag{
+						Name:  "no-digits",
+						Usage: "do not use digits",
+					},
+					&cli.BoolFlag{
+						Name:    "no-ambiguous",
+						Aliases: []string{"a"},
+						Usage:   "do not use characters that are easily confused, like 0 and O",
+					},
+					&cli.BoolFlag{
+						Name:    "passphrase",
+						Aliases: []string{"p"},
+						Usage:   "generate a passphrase of words from the bundled wordlist",
+					},
+					&cli.StringFlag{
+						Name:  "separator",
+						Usage: "separator between passphrase words",
+						Value: "-",
+					},
+					&cli.BoolFlag{
+						Name:    "in-place",
+						Aliases: []string{"i"},
+						Usage:   "replace only the first line of an existing entry",
+					},
+					&cli.BoolFlag{
+						Name:    "force",
+						Aliases: []string{"f"},
+						Usage:   "overwrite an existing entry",
+					},
+					&cli.BoolFlag{
+						Name:    "clip",
+						Aliases: []string{"c"},
+						Usage:   "copy the secret to the clipboard instead of printing it",
+					},
+					clipTimeoutFlag,
+				},
+				Action: func(c *cli.Context) error {
+					if c.Args().Len() < 1 || c.Args().Len() > 2 {
+						return errors.New("invalid number of arguments")
+					}
+					name := c.Args().Get(0)
+					length := 24
+					if c.Bool("passphrase") {
+						length = 6
+					}
+					if c.Args().Len() == 2 {
+						var err error
+						length, err = strconv.Atoi(c.Args().Get(1))
+						if err != nil {
+							return fmt.Errorf("invalid length: %w", err)
+						}
+					}
+					store, err := openStore(c, c.Bool("in-place"))
+					if err != nil {
+						return err
+					}
+					var entry pass.Entry
+					switch {
+					case c.Bool("in-place"):
+						data, err := store.Decrypt(name)
+						if err != nil {
+							return err
+						}
+						entry = pass.ParseEntry(data)
+					case !c.Bool("force") && store.Exists(name):
+						return fmt.Errorf("%w: %s", pass.ErrExists, name)
+					}
+					var entropy float64
+					if c.Bool("passphrase") {
+						entry.Secret, entropy, err = pass.GeneratePassphrase(length, c.String("separator"))
+					} else {
+						entry.Secret, entropy, err = pass.GeneratePassword(length, pass.PasswordOptions{
+							NoDigits:    c.Bool("no-digits"),
+							NoSymbols:   c.Bool("no-symbols"),
+							NoAmbiguous: c.Bool("no-ambiguous"),
+						})
+					}
+					if err != nil {
+						return err
+					}
+					err = store.Encrypt(name, entry.Bytes())
+					if err != nil {
+						return err
+					}
+					fmt.Fprintf(os.Stderr, "Generated %s with %.0f bits of entropy.\n", name, entropy)
+					if c.Bool("clip") {
+						return copySecret(name, []byte(entry.Secret), c.Duration("clip-timeout"))
+					}
+					fmt.Println(entry.Secret)
+					return nil
+				},
+			},
+			{
+				Name:      "otp",
+				Usage:     "print the current one-time password of an entry",
+				ArgsUsage: "name",
+				Description: "The entry has to hold an otpauth:// URI, either as a line of its own or as\n" +
+					"\"otpauth: otpauth://...\" metadata. For HOTP the counter is incremented and the\n" +
+					"entry re-encrypted.",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "clip",
+						Aliases: []string{"c"},
+						Usage:   "copy the code to the clipboard instead of printing it",
+					},
+					clipTimeoutFlag,
+				},
+				Action: func(c *cli.Context) error {
+					if c.Args().Len() != 1 {
+						return errors.New("invalid number of arguments")
+					}
+					store, err := openStore(c, true)
+					if err != nil {
+						return err
+					}
+					code, remaining, err := otpCode(store, c.Args().First())
+					if err != nil {
+						return err
+					}
+					if remaining > 0 {
+						fmt.Fprintf(os.Stderr, "valid for %ds\n", int(remaining.Seconds()))
+					}
+					if c.Bool("clip") {
+						timeout := c.Duration("clip-timeout")
+						if remaining > 0 && remaining < timeout {
+							timeout = remaining
+						}
+						return copySecret("one-time password", []byte(code), timeout)
+					}
+					fmt.Println(code)
+					return nil
+				},
+				Subcommands: []*cli.Command{
+					{
+						Name:      "insert",
+						Usage:     "add an otpauth URI or base32 TOTP secret to an entry, prompting for it if not given",
+						ArgsUsage: "name [uri|secret]",
+						Action: func(c *cli.Context) error {
+							if c.Args().Len() < 1 || c.Args().Len() > 2 {
+								return errors.New("invalid number of arguments")
+							}
+							name := c.Args().First()
+							value := c.Args().Get(1)
+							store, err := openStore(c, false)
+							if err != nil {
+								return err
+							}
+							if store.Exists(name) {
+								store.Identities, err = loadIdentities(c)
+								if err != nil {
+									return err
+								}
+							}
+							if value == "" {
+								value, err = readSecret("otpauth URI or secret for " + name + ": ")
+								if err != nil {
+									return err
+								}
+							}
+							uri := strings.TrimSpace(value)
+							if !strings.HasPrefix(uri, "otpauth://") {
+								uri, err = pass.NewTOTPURI(name, uri)
+								if err != nil {
+									return err
+								}
+							}
+							_, err = pass.ParseOTP(uri)
+							if err != nil {
+								return err
+							}
+							entry := pass.Entry{Secret: uri}
+							if store.Exists(name) {
+								data, err := store.Decrypt(name)
+								if err != nil {
+									return err
+								}
+								entry = pass.ParseEntry(data)
+								entry.SetOTP(uri)
+							}
+							return store.Encrypt(name, entry.Bytes())
+						},
+					},
+				},
+			},
+			{
+				Name:      "reencrypt",
+				Usage:     "re-encrypt entries to the current recipients of their directory",
+				ArgsUsage: "[subdir]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "dry-run",
+						Aliases: []string{"n"},
+						Usage:   "only list the entries that would be re-encrypted",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					store, err := openStore(c, !c.Bool("dry-run"))
+					if err != nil {
+						return err
+					}
+					return reencrypt(store, c.Args().First(), "", c.Bool("dry-run"))
+				},
+			},
+			{
+				Name:  "import",
+				Usage: "import entries from another password manager",
+				Description: "Sources are a pass or gopass store directory (pass), a KeePass or KeePassXC CSV export\n" +
+					"(keepass), an unencrypted Bitwarden JSON export (bitwarden) or a 1Password CSV export\n" +
+					"(1password). Exports contain plaintext secrets, delete them after importing.",
+				ArgsUsage: "<pass|keepass|bitwarden|1password> <path>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "dry-run",
+						Aliases: []string{"n"},
+						Usage:   "only list the entries that would be imported",
+					},
+					&cli.StringFlag{
+						Name:  "collision",
+						Usage: "what to do with entries that already exist: skip, overwrite or rename",
+						Value: pass.CollisionSkip,
+					},
+					&cli.StringFlag{
+						Name:    "prefix",
+						Aliases: []string{"p"},
+						Usage:   "directory to import the entries into",
+					},
+					&cli.BoolFlag{
+						Name:  "plaintext",
+						Usage: "read the files of a pass store as already decrypted entries instead of using gpg",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if c.Args().Len() != 2 {
+						return errors.New("expected a source format and a path")
+					}
+					entries, err := readImport(c.Args().Get(0), c.Args().Get(1), c.Bool("plaintext"))
+					if err != nil {
+						return err
+					}
+					store, err := openStore(c, false)
+					if err != nil {
+						return err
+					}
+					results, err := store.Import(entries, c.String("prefix"), c.String("collision"), c.Bool("dry-run"))
+					if err != nil {
+						return err
+					}
+					failed := 0
+					for _, result := range results {
+						switch {
+						case result.Err != nil:
+							failed++
+							fmt.Printf("failed\t%s\t%v\n", result.Source, result.Err)
+						case result.Name == "":
+							fmt.Printf("skipped\t%s\n", result.Source)
+						case c.Bool("dry-run"):
+							fmt.Printf("would import\t%s\t%s\n", result.Source, result.Name)
+						default:
+							fmt.Printf("imported\t%s\t%s\n", result.Source, result.Name)
+						}
+					}
+					if failed > 0 {
+						return fmt.Errorf("failed to import %d of %d entries", failed, len(results))
+					}
+					return nil
+				},
+			},
+			{
+				Name:  "recipients",
+				Usage: "manage the recipients entries are encrypted to",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "path",
+						Aliases: []string{"p"},
+						Usage:   "subdirectory whose recipients to manage",
+					},
+				},
+				Subcommands: []*cli.Command{
+					{
+						Name:    "ls",
+						Aliases: []string{"list"},
+						Usage:   "list the recipients",
+						Action: func(c *cli.Context) error {
+							store, err := openStore(c, false)
+							if err != nil {
+								return err
+							}
+							file, recipients, err := store.RecipientLines(c.String("path"))
+							if err != nil {
+								return err
+							}
+							logger.Debug("recipients file", "path", file)
+							for _, recipient := range recipients {
+								fmt.Println(recipient)
+							}
+							return nil
+						},
+					},
+					{
+						Name:      "add",
+						Usage:     "add recipients and re-encrypt the affected entries",
+						ArgsUsage: "recipient...",
+						Flags:     recipientEditFlags,
+						Action: func(c *cli.Context) error {
+							return editRecipients(c, (*pass.Store).AddRecipients)
+						},
+					},
+					{
+						Name:      "rm",
+						Aliases:   []string{"remove"},
+						Usage:     "remove recipients and re-encrypt the affected entries",
+						ArgsUsage: "recipient...",
+						Flags:     recipientEditFlags,
+						Action: func(c *cli.Context) error {
+							return editRecipients(c, (*pass.Store).RemoveRecipients)
+						},
+					},
+				},
+			},
+			{
+				Name:            "git",
+				Usage:           "run git inside the store, entries are committed automatically once it is a repository",
+				ArgsUsage:       "args...",
+				SkipFlagParsing: true,
+				Description: "After \"pass git init\" every change to the store is committed and git diff\n" +
+					"shows decrypted entries through the git-diff-driver command.",
+				Action: func(c *cli.Context) error {
+					store, err := openStore(c, false)
+					if err != nil {
+						return err
+					}
+					cmd := store.Git(c.Args().Slice()...)
+					cmd.Stdin = os.Stdin
+					cmd.Stdout = os.Stdout
+					cmd.Stderr = os.Stderr
+					err = cmd.Run()
+					var exitErr *exec.ExitError
+					if errors.As(err, &exitErr) {
+						return cli.Exit("", exitErr.ExitCode())
+					}
+					if err != nil {
+						return err
+					}
+					if c.Args().First() == "init" {
+						command, err := diffDriverCommand(c)
+						if err != nil {
+							return err
+						}
+						return store.SetupGitDiff(command)
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "git-diff-driver",
+				Usage:     "decrypt an entry for git diff, used as textconv filter",
+				ArgsUsage: "file",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "install",
+						Usage: "configure the store repository to use this diff driver",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if c.Bool("install") {
+						store, err := openStore(c, false)
+						if err != nil {
+							return err
+						}
+						command, err := diffDriverCommand(c)
+						if err != nil {
+							return err
+						}
+						return store.SetupGitDiff(command)
+					}
+					if c.Args().Len() != 1 {
+						return errors.New("invalid number of arguments")
+					}
+					identities, err := loadIdentities(c)
+					if err != nil {
+						return err
+					}
+					return decryptFile(c.Args().First(), os.Stdout, pass.WithoutPrompts(identities))
+				},
+			},
+			{
+				Name: "decrypt",
+				Flags: []cli.Flag{
+					&cli.PathFlag{
+						Name:      "file",
+						Aliases:   []string{"f"},
+						Usage:     "file to decrypt",
+						TakesFile: true,
+						Required:  true,
+					},
+				},
+				Usage: "decrypt file",
+				Action: func(c *cli.Context) error {
+					identities, err := loadIdentities(c)
+					if err != nil {
+						return err
+					}
+					return decryptFile(c.String("file"), os.Stdout, identities)
+				},
+			},
+			{
+				Name:    "ui",
+				Aliases: []string{"u"},
+				Flags: []cli.Flag{
+					clipTimeoutFlag,
+				},
+				Usage: "start small fuzzy UI mostly to copy passwords out",
+				Action: func(c *cli.Context) error {
+					store, err := openStore(c, true)
+					if err != nil {
+						return err
+					}
+					names, err := store.List("")
+					if err != nil {
+						return err
+					}
+					if len(names) == 0 {
+						return errors.New("password store is empty")
+					}
+					previewStore := &pass.Store{Dir: store.Dir, Identities: pass.WithoutPrompts(store.Identities)}
+					previews := map[int]string{}
+					selected, err := fuzzyfinder.Find(names, func(i int) string {
+						return names[i]
+					}, fuzzyfinder.WithPreviewWindow(func(i, w, h int) string {
+						if i < 0 {
+							return ""
+						}
+						if preview, ok := previews[i]; ok {
+							return preview
+						}
+						data, err := previewStore.Decrypt(names[i])
+						if err != nil {
+							previews[i] = err.Error()
+						} else {
+							previews[i] = strings.Join(pass.ParseEntry(data).PublicMetadata(), "\n")
+						}
+						return previews[i]
+					}))
+					if err != nil {
+						if errors.Is(err, fuzzyfinder.ErrAbort) {
+							return nil
+						}
+						return fmt.Errorf("failed to select entry: %w", err)
+					}
+					name := names[selected]
+					data, err := store.Decrypt(name)
+					if err != nil {
+						return err
+					}
+					entry := pass.ParseEntry(data)
+					if _, err := entry.OTP(); err == nil {
+						actions := []string{"copy password", "copy OTP"}
+						action, err := fuzzyfinder.Find(actions, func(i int) string {
+							return actions[i]
+						}, fuzzyfinder.WithHeader(name))
+						if err != nil {
+							if errors.Is(err, fuzzyfinder.ErrAbort) {
+								return nil
+							}
+							return fmt.Errorf("failed to select action: %w", err)
+						}
+						if actions[action] == "copy OTP" {
+							code, remaining, err := otpCode(store, name)
+							if err != nil {
+								return err
+							}
+							timeout := c.Duration("clip-timeout")
+							if remaining > 0 && remaining < timeout {
+								timeout = remaining
+							}
+							return copySecret("one-time password of "+name, []byte(code), timeout)
+						}
+					}
+					return copySecret(name, []byte(entry.Secret), c.Duration("clip-timeout"))
+				},
+			},
+		},
+	}
+}
+
+var clipTimeoutFlag = &cli.DurationFlag{
+	Name:    "clip-timeout",
+	Aliases: []string{"t"},
+	Usage:   "clear the clipboard after this duration if it still holds the secret, 0 to keep it (on Linux and BSD by waiting until the clipboard changes)",
+	Value:   45 * time.Second,
+	EnvVars: []string{"SHELL_TOOLS_PASS_CLIP_TIMEOUT"},
+}
+
+var recipientEditFlags = []cli.Flag{
+	&cli.BoolFlag{
+		Name:  "no-reencrypt",
+		Usage: "only change the recipients file, do not re-encrypt entries",
+	},
+	&cli.BoolFlag{
+		Name:    "dry-run",
+		Aliases: []string{"n"},
+		Usage:   "only list the entries that would be re-encrypted",
+	},
+}
+
+func editRecipients(c *cli.Context, edit func(*pass.Store, string, []string) (string, error)) error {
+	if c.Args().Len() == 0 {
+		return errors.New("no recipients given")
+	}
+	store, err := openStore(c, !c.Bool("no-reencrypt") && !c.Bool("dry-run"))
+	if err != nil {
+		return err
+	}
+	if c.Bool("dry-run") {
+		file, _, err := store.RecipientLines(c.String("path"))
+		if err != nil {
+			return err
+		}
+		return reencrypt(store, c.String("path"), file, true)
+	}
+	file, err := edit(store, c.String("path"), c.Args().Slice())
+	if err != nil {
+		return err
+	}
+	if c.Bool("no-reencrypt") {
+		return nil
+	}
+	return reencrypt(store, c.String("path"), file, false)
+}
+
+// reencrypt re-encrypts the entries below subdir and reports every entry
+// on stdout.
+func reencrypt(store *pass.Store, subdir, only string, dryRun bool) error {
+	results, err := store.Reencrypt(subdir, only, dryRun)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, result := range results {
+		rel, err := filepath.Rel(store.Dir, result.RecipientsFile)
+		if err != nil {
+			rel = result.RecipientsFile
+		}
+		switch {
+		case result.Err != nil:
+			failed++
+			fmt.Printf("failed\t%s\t%v\n", result.Name, result.Err)
+		case dryRun:
+			fmt.Printf("would re-encrypt\t%s\t%s\n", result.Name, rel)
+		default:
+			fmt.Printf("re-encrypted\t%s\t%s\n", result.Name, rel)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to re-encrypt %d of %d entries", failed, len(results))
+	}
+	return nil
+}
+
+// readImport reads the entries to import from path in the given format.
+func readImport(format, path string, plaintext bool) ([]pass.ImportedEntry, error) {
+	if format == "pass" || format == "gopass" {
+		return pass.ReadPassStore(path, plaintext)
+	}
+	var parse func(io.Reader) ([]pass.ImportedEntry, error)
+	switch format {
+	case "keepass", "keepassxc":
+		parse = pass.ParseKeePassCSV
+	case "bitwarden":
+		parse = pass.ParseBitwardenJSON
+	case "1password":
+		parse = pass.ParseOnePasswordCSV
+	default:
+		return nil, fmt.Errorf("unknown import format: %s", format)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open export: %w", err)
+	}
+	defer file.Close()
+	return parse(file)
+}
+
+// otpCode returns the current one-time password of the entry name and how
+// long it stays valid. HOTP entries have no expiry and are re-encrypted with
+// their counter incremented.
+func otpCode(store *pass.Store, name string) (string, time.Duration, error) {
+	data, err := store.Decrypt(name)
+	if err != nil {
+		return "", 0, err
+	}
+	entry := pass.ParseEntry(data)
+	otp, err := entry.OTP()
+	if err != nil {
+		return "", 0, fmt.Errorf("%s: %w", name, err)
+	}
+	if otp.Type == "hotp" {
+		code := otp.HOTP(otp.Counter)
+		otp.Counter++
+		entry.SetOTP(otp.URI())
+		return code, 0, store.Encrypt(name, entry.Bytes())
+	}
+	code, remaining := otp.TOTP(time.Now())
+	return code, remaining, nil
+}
+
+// diffDriverCommand returns the command line git runs to decrypt entries,
+// keeping an explicitly configured identity file.
+func diffDriverCommand(c *cli.Context) (string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to get executable: %w", err)
+	}
+	command, err := syntax.Quote(executable, syntax.LangPOSIX)
+	if err != nil {
+		return "", err
+	}
+	command += " pass"
+	if c.String("identities") != "" {
+		identities, err := filepath.Abs(c.String("identities"))
+		if err != nil {
+			return "", err
+		}
+		quoted, err := syntax.Quote(identities, syntax.LangPOSIX)
+		if err != nil {
+			return "", err
+		}
+		command += " --identities " + quoted
+	}
+	return command + " git-diff-driver", nil
+}
+
+// loadIdentities loads the identities configured for the pass command,
+// asking for passphrases on the terminal.
+func loadIdentities(c *cli.Context) ([]age.Identity, error) {
+	path := c.String("identities")
+	if path == "" {
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get config dir: %w", err)
+		}
+		path = filepath.Join(configDir, "shell-tools", "identities")
+	}
+	return pass.LoadIdentities(path, func() (string, error) {
+		return readSecret("Enter passphrase: ")
+	})
+}
+
+// openStore opens the configured password store. Identities are only loaded
+// if they are needed to decrypt entries.
+func openStore(c *cli.Context, withIdentities bool) (*pass.Store, error) {
+	dir := c.String("store")
+	if dir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get home dir: %w", err)
+		}
+		dir = filepath.Join(homeDir, ".age-store")
+	}
+	store := &pass.Store{Dir: dir}
+	if withIdentities {
+		identities, err := loadIdentities(c)
+		if err != nil {
+			return nil, err
+		}
+		store.Identities = identities
+	}
+	return store, nil
+}
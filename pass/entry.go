@@ -0,0 +1,71 @@
+package pass
+
+import (
+	"strings"
+)
+
+// Entry is the content of a store entry. Following the pass convention the
+// first line is the secret, all following lines are metadata, usually in the
+// form "key: value".
+type Entry struct {
+	Secret   string
+	Metadata []string
+}
+
+// ParseEntry splits decrypted entry data into the secret and its metadata.
+func ParseEntry(data []byte) Entry {
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	entry := Entry{Secret: lines[0]}
+	if len(lines) > 1 {
+		entry.Metadata = lines[1:]
+	}
+	return entry
+}
+
+// Bytes encodes the entry back into its stored form.
+func (e Entry) Bytes() []byte {
+	var b strings.Builder
+	b.WriteString(e.Secret)
+	b.WriteByte('\n')
+	for _, line := range e.Metadata {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return []byte(b.String())
+}
+
+// Field returns the value of the first metadata line of the form
+// "key: value", comparing keys case-insensitively.
+func (e Entry) Field(key string) (string, bool) {
+	for _, line := range e.Metadata {
+		k, v, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(k), key) {
+			return strings.TrimSpace(v), true
+		}
+	}
+	return "", false
+}
+
+// PublicMetadata returns the metadata lines that are safe to display.
+func (e Entry) PublicMetadata() []string {
+	var public []string
+	for _, line := range e.Metadata {
+		if isSecretLine(line) {
+			continue
+		}
+		public = append(public, line)
+	}
+	return public
+}
+
+func isSecretLine(line string) bool {
+	key, _, ok := strings.Cut(line, ":")
+	if !ok {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(key)) {
+	case "otpauth", "password", "secret", "pin", "totp", "recovery":
+		return true
+	}
+	return false
+}
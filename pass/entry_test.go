@@ -0,0 +1,24 @@
+package pass
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEntry(t *testing.T) {
+	entry := ParseEntry([]byte("hunter2\nuser: alice\nURL: https://example.com\notpauth: otpauth://totp/x?secret=ABC\n"))
+	assert.Equal(t, "hunter2", entry.Secret)
+	user, ok := entry.Field("User")
+	assert.True(t, ok)
+	assert.Equal(t, "alice", user)
+	url, ok := entry.Field("url")
+	assert.True(t, ok)
+	assert.Equal(t, "https://example.com", url)
+	assert.Equal(t, []string{"user: alice", "URL: https://example.com"}, entry.PublicMetadata())
+	assert.Equal(t, "hunter2\nuser: alice\nURL: https://example.com\notpauth: otpauth://totp/x?secret=ABC\n", string(entry.Bytes()))
+
+	entry = ParseEntry([]byte("only"))
+	assert.Equal(t, "only", entry.Secret)
+	assert.Empty(t, entry.Metadata)
+}
@@ -0,0 +1,109 @@
+package pass
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"errors"
+	"math"
+	"math/big"
+	"strings"
+)
+
+const (
+	lowercase = "abcdefghijklmnopqrstuvwxyz"
+	uppercase = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	digits    = "0123456789"
+	symbols   = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+	ambiguous = "0O1lI|`'\""
+)
+
+// wordlist is the BIP39 list of 2048 english words, which are short, common
+// and unambiguous after their first four letters.
+//
+//go:embed wordlist.txt
+var wordlist string
+
+var words = strings.Fields(wordlist)
+
+// PasswordOptions selects the character classes of generated passwords.
+// Letters are always used.
+type PasswordOptions struct {
+	NoDigits    bool
+	NoSymbols   bool
+	NoAmbiguous bool
+}
+
+// GeneratePassword returns a random password of length characters and its
+// entropy in bits. Every enabled character class occurs at least once if
+// the password is long enough.
+func GeneratePassword(length int, opts PasswordOptions) (string, float64, error) {
+	if length <= 0 {
+		return "", 0, errors.New("length must be positive")
+	}
+	classes := []string{lowercase, uppercase}
+	if !opts.NoDigits {
+		classes = append(classes, digits)
+	}
+	if !opts.NoSymbols {
+		classes = append(classes, symbols)
+	}
+	if opts.NoAmbiguous {
+		for i, class := range classes {
+			classes[i] = strings.Map(func(r rune) rune {
+				if strings.ContainsRune(ambiguous, r) {
+					return -1
+				}
+				return r
+			}, class)
+		}
+	}
+	charset := strings.Join(classes, "")
+	entropy := float64(length) * math.Log2(float64(len(charset)))
+	for {
+		password := make([]byte, length)
+		for i := range password {
+			n, err := randomInt(len(charset))
+			if err != nil {
+				return "", 0, err
+			}
+			password[i] = charset[n]
+		}
+		if length < len(classes) || containsAll(string(password), classes) {
+			return string(password), entropy, nil
+		}
+	}
+}
+
+func containsAll(password string, classes []string) bool {
+	for _, class := range classes {
+		if !strings.ContainsAny(password, class) {
+			return false
+		}
+	}
+	return true
+}
+
+// GeneratePassphrase returns count random words from the bundled wordlist
+// joined by separator, and its entropy in bits.
+func GeneratePassphrase(count int, separator string) (string, float64, error) {
+	if count <= 0 {
+		return "", 0, errors.New("word count must be positive")
+	}
+	chosen := make([]string, count)
+	for i := range chosen {
+		n, err := randomInt(len(words))
+		if err != nil {
+			return "", 0, err
+		}
+		chosen[i] = words[n]
+	}
+	return strings.Join(chosen, separator), float64(count) * math.Log2(float64(len(words))), nil
+}
+
+func randomInt(max int) (int, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(max)))
+	if err != nil {
+		return 0, err
+	}
+	return int(n.Int64()), nil
+}
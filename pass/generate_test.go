@@ -0,0 +1,34 @@
+package pass
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratePassword(t *testing.T) {
+	password, entropy, err := GeneratePassword(32, PasswordOptions{})
+	require.NoError(t, err)
+	assert.Len(t, password, 32)
+	assert.True(t, strings.ContainsAny(password, digits))
+	assert.True(t, strings.ContainsAny(password, symbols))
+	assert.InDelta(t, 32*math.Log2(94), entropy, 0.001)
+
+	password, _, err = GeneratePassword(64, PasswordOptions{NoSymbols: true, NoDigits: true, NoAmbiguous: true})
+	require.NoError(t, err)
+	assert.False(t, strings.ContainsAny(password, digits+symbols+ambiguous))
+
+	_, _, err = GeneratePassword(0, PasswordOptions{})
+	assert.Error(t, err)
+}
+
+func TestGeneratePassphrase(t *testing.T) {
+	require.Len(t, words, 2048)
+	passphrase, entropy, err := GeneratePassphrase(5, " ")
+	require.NoError(t, err)
+	assert.Len(t, strings.Split(passphrase, " "), 5)
+	assert.Equal(t, 55.0, entropy)
+}
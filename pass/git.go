@@ -0,0 +1,81 @@
+package pass
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// gitAttributes makes git diff entries through the "age" diff driver.
+const gitAttributes = "*" + Extension + " diff=age\n"
+
+// UsesGit reports whether the store directory is a git repository. Every
+// change to such a store is committed automatically.
+func (s *Store) UsesGit() bool {
+	_, err := os.Stat(filepath.Join(s.Dir, ".git"))
+	return err == nil
+}
+
+// Git returns a git command running in the store directory.
+func (s *Store) Git(args ...string) *exec.Cmd {
+	return exec.Command("git", append([]string{"-C", s.Dir}, args...)...)
+}
+
+func (s *Store) git(args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := s.Git(args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// commit commits all changes of the store if it uses git.
+func (s *Store) commit(message string) error {
+	if !s.UsesGit() {
+		return nil
+	}
+	_, err := s.git("add", "--all", ".")
+	if err != nil {
+		return err
+	}
+	status, err := s.git("status", "--porcelain")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(status) == "" {
+		return nil
+	}
+	_, err = s.git("commit", "--quiet", "--message", message)
+	return err
+}
+
+// SetupGitDiff registers command as textconv filter for entries, so git diff
+// and git log -p show decrypted changes. The filter is part of the local git
+// config only, the .gitattributes file selecting it is committed.
+func (s *Store) SetupGitDiff(command string) error {
+	path := filepath.Join(s.Dir, ".gitattributes")
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if !bytes.Contains(data, []byte(gitAttributes)) {
+		if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+			data = append(data, '\n')
+		}
+		err = os.WriteFile(path, append(data, gitAttributes...), 0o644)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = s.git("config", "diff.age.textconv", command)
+	if err != nil {
+		return err
+	}
+	return s.commit("Configure age diff driver")
+}
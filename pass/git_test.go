@@ -0,0 +1,60 @@
+package pass
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupGit(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", "/dev/null")
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+}
+
+func TestStoreGitCommits(t *testing.T) {
+	setupGit(t)
+	store, _ := newTestStore(t)
+	remote := filepath.Join(t.TempDir(), "remote.git")
+	require.NoError(t, exec.Command("git", "init", "--quiet", "--bare", remote).Run())
+	_, err := store.git("init", "--quiet")
+	require.NoError(t, err)
+	assert.True(t, store.UsesGit())
+	require.NoError(t, store.SetupGitDiff("cat"))
+
+	require.NoError(t, store.Insert("mail", []byte("one\n"), false))
+	require.NoError(t, store.Encrypt("mail", []byte("two\n")))
+	require.NoError(t, store.Move("mail", "web/mail", false))
+	require.NoError(t, store.Remove("web/mail", false))
+
+	log, err := store.git("log", "--format=%s")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"Remove web/mail",
+		"Move mail to web/mail",
+		"Update mail",
+		"Add mail",
+		"Configure age diff driver",
+	}, strings.Split(strings.TrimSpace(log), "\n"))
+
+	attributes, err := store.git("check-attr", "diff", "--", "x.age")
+	require.NoError(t, err)
+	assert.Equal(t, "x.age: diff: age\n", attributes)
+
+	_, err = store.git("push", "--quiet", remote, "HEAD:refs/heads/main")
+	require.NoError(t, err)
+	out, err := exec.Command("git", "-C", remote, "log", "--format=%s", "-1", "main").Output()
+	require.NoError(t, err)
+	assert.Equal(t, "Remove web/mail\n", string(out))
+}
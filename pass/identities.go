@@ -0,0 +1,83 @@
+package pass
+
+import (
+	"bytes"
+	"errors"
+	"filippo.io/age"
+	"fmt"
+	"io"
+	"os"
+	"tasadar.net/tionis/shell-tools/crypt"
+)
+
+// ErrNoIdentities is returned if no identity file is configured.
+var ErrNoIdentities = errors.New("no identity file configured")
+
+const encryptedPrefix = "age-encryption.org/v1\n"
+
+// LoadIdentities reads the X25519 identities in the file at path, one per
+// line, or the SSH private key in it. An identity file that is itself
+// encrypted with a passphrase is decrypted first, asking passphrase for it.
+// The returned identities always include a scrypt identity, so entries
+// encrypted with a passphrase can be decrypted as well.
+func LoadIdentities(path string, passphrase func() (string, error)) ([]age.Identity, error) {
+	if path == "" {
+		return nil, ErrNoIdentities
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identity file: %w", err)
+	}
+	if bytes.HasPrefix(data, []byte(encryptedPrefix)) {
+		decrypted, err := age.Decrypt(bytes.NewReader(data), &LazyScryptIdentity{Passphrase: passphrase})
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt identity file: %w", err)
+		}
+		data, err = io.ReadAll(decrypted)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt identity file: %w", err)
+		}
+	}
+	identities, err := crypt.ParseIdentities(data, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse identity file %s: %w", path, err)
+	}
+	return append(identities, &LazyScryptIdentity{Passphrase: passphrase}), nil
+}
+
+// LazyScryptIdentity is a scrypt identity that only asks for its passphrase
+// once it is needed to decrypt a passphrase-encrypted file.
+type LazyScryptIdentity struct {
+	Passphrase func() (string, error)
+}
+
+// Unwrap implements age.Identity.
+func (i *LazyScryptIdentity) Unwrap(stanzas []*age.Stanza) ([]byte, error) {
+	if len(stanzas) != 1 || stanzas[0].Type != "scrypt" {
+		return nil, age.ErrIncorrectIdentity
+	}
+	if i.Passphrase == nil {
+		return nil, errors.New("file is encrypted with a passphrase, but none can be asked for")
+	}
+	passphrase, err := i.Passphrase()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return identity.Unwrap(stanzas)
+}
+
+// WithoutPrompts returns identities without those that would ask for a
+// passphrase, for use where no prompt can be shown.
+func WithoutPrompts(identities []age.Identity) []age.Identity {
+	var filtered []age.Identity
+	for _, identity := range identities {
+		if _, ok := identity.(*LazyScryptIdentity); !ok {
+			filtered = append(filtered, identity)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,361 @@
+package pass
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"tasadar.net/tionis/shell-tools/convert"
+)
+
+// Collision policies deciding what happens to imported entries whose name
+// is already taken.
+const (
+	CollisionSkip      = "skip"
+	CollisionOverwrite = "overwrite"
+	CollisionRename    = "rename"
+)
+
+// ImportedEntry is an entry read from another password manager.
+type ImportedEntry struct {
+	Name  string
+	Entry Entry
+	// Err is set if the entry could not be read, it is reported as failed
+	// by [Store.Import].
+	Err error
+}
+
+// ImportResult is the outcome of importing a single entry.
+type ImportResult struct {
+	// Source is the name of the entry in the source.
+	Source string
+	// Name is the name the entry is stored as, empty if it was skipped.
+	Name string
+	Err  error
+}
+
+// Import encrypts entries into the store below prefix, handling names that
+// are already taken according to collision. With dryRun nothing is written.
+func (s *Store) Import(entries []ImportedEntry, prefix, collision string, dryRun bool) ([]ImportResult, error) {
+	switch collision {
+	case CollisionSkip, CollisionOverwrite, CollisionRename:
+	default:
+		return nil, fmt.Errorf("invalid collision policy: %s", collision)
+	}
+	planned := map[string]bool{}
+	taken := func(name string) bool {
+		return planned[name] || s.Exists(name)
+	}
+	var results []ImportResult
+	imported := 0
+	for _, entry := range entries {
+		name := entry.Name
+		if prefix = strings.Trim(prefix, "/"); prefix != "" {
+			name = prefix + "/" + name
+		}
+		if entry.Err != nil {
+			results = append(results, ImportResult{Source: entry.Name, Err: entry.Err})
+			continue
+		}
+		result := ImportResult{Source: entry.Name, Name: name}
+		if taken(name) {
+			switch collision {
+			case CollisionSkip:
+				result.Name = ""
+			case CollisionRename:
+				for i := 2; taken(name); i++ {
+					name = result.Name + "-" + strconv.Itoa(i)
+				}
+				result.Name = name
+			}
+		}
+		if result.Name != "" {
+			planned[result.Name] = true
+			if !dryRun {
+				result.Err = s.encryptEntry(result.Name, entry.Entry.Bytes())
+				if result.Err == nil {
+					imported++
+				}
+			}
+		}
+		results = append(results, result)
+	}
+	if imported == 0 {
+		return results, nil
+	}
+	return results, s.commit(fmt.Sprintf("Import %d entries", imported))
+}
+
+func (s *Store) encryptEntry(name string, data []byte) error {
+	recipients, err := s.Recipients(name)
+	if err != nil {
+		return err
+	}
+	return s.encryptTo(name, data, recipients)
+}
+
+// entryName turns a group path and title into an entry name, so titles
+// containing slashes do not create directories.
+func entryName(group, title string) string {
+	var parts []string
+	for _, part := range strings.Split(group, "/") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	// KeePass puts everything below a group called Root.
+	if len(parts) > 0 && parts[0] == "Root" {
+		parts = parts[1:]
+	}
+	title = strings.TrimSpace(strings.ReplaceAll(title, "/", "-"))
+	if title == "" || title == "." || title == ".." {
+		title = "untitled"
+	}
+	return strings.Join(append(parts, title), "/")
+}
+
+// newEntry builds an entry following the first-line-secret convention.
+func newEntry(secret string, fields [][2]string, otp, notes string) Entry {
+	entry := Entry{Secret: secret}
+	for _, field := range fields {
+		value := strings.TrimSpace(strings.ReplaceAll(field[1], "\n", " "))
+		if value != "" {
+			entry.Metadata = append(entry.Metadata, field[0]+": "+value)
+		}
+	}
+	if otp = strings.TrimSpace(otp); otp != "" {
+		if !strings.HasPrefix(otp, "otpauth://") {
+			uri, err := NewTOTPURI("imported", otp)
+			if err == nil {
+				otp = uri
+			}
+		}
+		entry.Metadata = append(entry.Metadata, otp)
+	}
+	if notes = strings.TrimSpace(notes); notes != "" {
+		entry.Metadata = append(entry.Metadata, strings.Split(notes, "\n")...)
+	}
+	return entry
+}
+
+// csvRecords reads a CSV export with a header row through the convert
+// package. Columns are looked up case-insensitively.
+func csvRecords(r io.Reader) ([]map[string]string, error) {
+	data, err := convert.Decode("csv", nil, r)
+	if err != nil {
+		return nil, err
+	}
+	rows, ok := data.([]interface{})
+	if !ok {
+		return nil, errors.New("unexpected csv structure")
+	}
+	records := make([]map[string]string, 0, len(rows))
+	for _, row := range rows {
+		record := map[string]string{}
+		for key, value := range row.(map[string]interface{}) {
+			record[strings.ToLower(strings.TrimSpace(key))] = fmt.Sprint(value)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// column returns the first non-empty value of the given columns.
+func column(record map[string]string, names ...string) string {
+	for _, name := range names {
+		if value := record[name]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// ParseKeePassCSV reads a KeePass or KeePassXC CSV export.
+func ParseKeePassCSV(r io.Reader) ([]ImportedEntry, error) {
+	records, err := csvRecords(r)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]ImportedEntry, 0, len(records))
+	for _, record := range records {
+		entries = append(entries, ImportedEntry{
+			Name: entryName(column(record, "group"), column(record, "title", "account")),
+			Entry: newEntry(
+				column(record, "password"),
+				[][2]string{
+					{"user", column(record, "username", "login name", "user name")},
+					{"url", column(record, "url", "web site")},
+				},
+				column(record, "totp"),
+				column(record, "notes", "comments")),
+		})
+	}
+	return entries, nil
+}
+
+// ParseOnePasswordCSV reads a 1Password CSV export.
+func ParseOnePasswordCSV(r io.Reader) ([]ImportedEntry, error) {
+	records, err := csvRecords(r)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]ImportedEntry, 0, len(records))
+	for _, record := range records {
+		entries = append(entries, ImportedEntry{
+			Name: entryName(column(record, "vault"), column(record, "title", "name")),
+			Entry: newEntry(
+				column(record, "password"),
+				[][2]string{
+					{"user", column(record, "username")},
+					{"url", column(record, "url", "website", "urls")},
+					{"tags", column(record, "tags")},
+				},
+				column(record, "otpauth", "one-time password", "otp"),
+				column(record, "notes", "notesplain")),
+		})
+	}
+	return entries, nil
+}
+
+type bitwardenExport struct {
+	Encrypted bool `json:"encrypted"`
+	Folders   []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"folders"`
+	Items []struct {
+		Type     int     `json:"type"`
+		Name     string  `json:"name"`
+		FolderID *string `json:"folderId"`
+		Notes    *string `json:"notes"`
+		Login    *struct {
+			Username *string `json:"username"`
+			Password *string `json:"password"`
+			TOTP     *string `json:"totp"`
+			URIs     []struct {
+				URI string `json:"uri"`
+			} `json:"uris"`
+		} `json:"login"`
+		Fields []struct {
+			Name  string  `json:"name"`
+			Value *string `json:"value"`
+		} `json:"fields"`
+	} `json:"items"`
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// ParseBitwardenJSON reads an unencrypted Bitwarden JSON export. Logins and
+// secure notes are imported, other item types are ignored.
+func ParseBitwardenJSON(r io.Reader) ([]ImportedEntry, error) {
+	var export bitwardenExport
+	err := json.NewDecoder(r).Decode(&export)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse bitwarden export: %w", err)
+	}
+	if export.Encrypted {
+		return nil, errors.New("bitwarden export is encrypted, export it unencrypted")
+	}
+	folders := map[string]string{}
+	for _, folder := range export.Folders {
+		folders[folder.ID] = folder.Name
+	}
+	var entries []ImportedEntry
+	for _, item := range export.Items {
+		var secret, otp string
+		var fields [][2]string
+		switch item.Type {
+		case 1:
+			if item.Login != nil {
+				secret = deref(item.Login.Password)
+				otp = deref(item.Login.TOTP)
+				fields = append(fields, [2]string{"user", deref(item.Login.Username)})
+				for _, uri := range item.Login.URIs {
+					fields = append(fields, [2]string{"url", uri.URI})
+				}
+			}
+		case 2:
+		default:
+			continue
+		}
+		for _, field := range item.Fields {
+			fields = append(fields, [2]string{field.Name, deref(field.Value)})
+		}
+		entries = append(entries, ImportedEntry{
+			Name:  entryName(folders[deref(item.FolderID)], item.Name),
+			Entry: newEntry(secret, fields, otp, deref(item.Notes)),
+		})
+	}
+	return entries, nil
+}
+
+// ReadPassStore reads the entries of a pass store at dir. Entries are
+// decrypted with the gpg binary, unless plaintext is set, in which case
+// every file is taken as an already decrypted entry. Entries that fail to
+// decrypt are returned with their error.
+func ReadPassStore(dir string, plaintext bool) ([]ImportedEntry, error) {
+	var entries []ImportedEntry
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(entry.Name(), ".") && path != dir {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() || (!plaintext && filepath.Ext(path) != ".gpg") {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		var data []byte
+		if plaintext {
+			data, err = os.ReadFile(path)
+		} else {
+			data, err = gpgDecrypt(path)
+		}
+		imported := ImportedEntry{Name: filepath.ToSlash(strings.TrimSuffix(rel, filepath.Ext(rel)))}
+		if err != nil {
+			imported.Err = fmt.Errorf("failed to read %s: %w", rel, err)
+		} else {
+			imported.Entry = ParseEntry(data)
+		}
+		entries = append(entries, imported)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
+
+func gpgDecrypt(path string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("gpg", "--quiet", "--batch", "--yes", "--decrypt", path)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("gpg failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
@@ -0,0 +1,120 @@
+package pass
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKeePassCSV(t *testing.T) {
+	entries, err := ParseKeePassCSV(strings.NewReader("\ufeff\"Group\",\"Title\",\"Username\",\"Password\",\"URL\",\"Notes\",\"TOTP\"\n" +
+		"\"Root/Web\",\"example.com/login\",\"alice\",\"hunter2\",\"https://example.com\",\"line one\nline two\",\"JBSWY3DPEHPK3PXP\"\n" +
+		"\"Root\",\"\",\"\",\"secret\",\"\",\"\",\"\"\n"))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "Web/example.com-login", entries[0].Name)
+	assert.Equal(t, "hunter2\nuser: alice\nurl: https://example.com\notpauth://totp/imported?secret=JBSWY3DPEHPK3PXP\nline one\nline two\n",
+		string(entries[0].Entry.Bytes()))
+	assert.Equal(t, "untitled", entries[1].Name)
+}
+
+func TestParseBitwardenJSON(t *testing.T) {
+	entries, err := ParseBitwardenJSON(strings.NewReader(`{
+		"encrypted": false,
+		"folders": [{"id": "f1", "name": "Mail"}],
+		"items": [
+			{"type": 1, "name": "Example", "folderId": "f1", "notes": null,
+			 "login": {"username": "alice", "password": "hunter2", "totp": "otpauth://totp/x?secret=JBSWY3DPEHPK3PXP",
+			           "uris": [{"uri": "https://mail.example.com"}]},
+			 "fields": [{"name": "pin", "value": "1234"}]},
+			{"type": 2, "name": "Note", "folderId": null, "notes": "just text"},
+			{"type": 3, "name": "Card"}
+		]
+	}`))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "Mail/Example", entries[0].Name)
+	assert.Equal(t, "hunter2\nuser: alice\nurl: https://mail.example.com\npin: 1234\notpauth://totp/x?secret=JBSWY3DPEHPK3PXP\n",
+		string(entries[0].Entry.Bytes()))
+	assert.Equal(t, "Note", entries[1].Name)
+	assert.Equal(t, "\njust text\n", string(entries[1].Entry.Bytes()))
+
+	_, err = ParseBitwardenJSON(strings.NewReader(`{"encrypted": true}`))
+	assert.Error(t, err)
+}
+
+func TestReadPassStorePlaintext(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "web"), 0o700))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".git"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "web", "example.com.gpg"), []byte("hunter2\nuser: alice\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".git", "config"), []byte("x"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".gpg-id"), []byte("x"), 0o600))
+
+	entries, err := ReadPassStore(dir, true)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "web/example.com", entries[0].Name)
+	assert.Equal(t, "hunter2", entries[0].Entry.Secret)
+
+	// Entries failing to decrypt do not stop the import of the others.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.gpg"), []byte("not gpg"), 0o600))
+	entries, err = ReadPassStore(dir, false)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "broken", entries[0].Name)
+	assert.Error(t, entries[0].Err)
+	store, _ := newTestStore(t)
+	results, err := store.Import([]ImportedEntry{entries[0], {Name: "ok", Entry: Entry{Secret: "ok"}}}, "", CollisionSkip, false)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Error(t, results[0].Err)
+	assert.Equal(t, "", results[0].Name)
+	assert.NoError(t, results[1].Err)
+	assert.True(t, store.Exists("ok"))
+}
+
+func TestImportCollisions(t *testing.T) {
+	store, _ := newTestStore(t)
+	require.NoError(t, store.Insert("imported/a", []byte("old\n"), false))
+	entries := []ImportedEntry{
+		{Name: "a", Entry: Entry{Secret: "new"}},
+		{Name: "a", Entry: Entry{Secret: "newer"}},
+		{Name: "b", Entry: Entry{Secret: "b"}},
+	}
+
+	results, err := store.Import(entries, "imported", CollisionRename, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"imported/a-2", "imported/a-3", "imported/b"}, importedNames(t, results))
+	assert.False(t, store.Exists("imported/b"))
+
+	results, err = store.Import(entries, "imported", CollisionSkip, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"", "", "imported/b"}, importedNames(t, results))
+	data, err := store.Decrypt("imported/a")
+	require.NoError(t, err)
+	assert.Equal(t, "old\n", string(data))
+
+	_, err = store.Import(entries[:1], "imported", CollisionOverwrite, false)
+	require.NoError(t, err)
+	data, err = store.Decrypt("imported/a")
+	require.NoError(t, err)
+	assert.Equal(t, "new\n", string(data))
+
+	_, err = store.Import(entries, "", "merge", false)
+	assert.Error(t, err)
+}
+
+func importedNames(t *testing.T, results []ImportResult) []string {
+	t.Helper()
+	names := make([]string, len(results))
+	for i, result := range results {
+		require.NoError(t, result.Err)
+		names[i] = result.Name
+	}
+	return names
+}
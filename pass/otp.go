@@ -0,0 +1,215 @@
+package pass
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoOTP is returned if an entry holds no otpauth URI.
+var ErrNoOTP = errors.New("entry has no otpauth URI")
+
+// OTP is a one-time password generator as described by an otpauth:// URI.
+type OTP struct {
+	// Type is either "totp" (RFC 6238) or "hotp" (RFC 4226).
+	Type      string
+	Label     string
+	Secret    []byte
+	Algorithm string
+	Digits    int
+	Period    time.Duration
+	Counter   uint64
+	params    url.Values
+}
+
+// ParseOTP parses an otpauth:// URI.
+func ParseOTP(uri string) (*OTP, error) {
+	u, err := url.Parse(strings.TrimSpace(uri))
+	if err != nil {
+		return nil, fmt.Errorf("invalid otpauth URI: %w", err)
+	}
+	if u.Scheme != "otpauth" {
+		return nil, fmt.Errorf("invalid otpauth URI scheme: %s", u.Scheme)
+	}
+	params := u.Query()
+	otp := &OTP{
+		Type:      strings.ToLower(u.Host),
+		Label:     strings.TrimPrefix(u.Path, "/"),
+		Algorithm: "SHA1",
+		Digits:    6,
+		Period:    30 * time.Second,
+		params:    params,
+	}
+	if otp.Type != "totp" && otp.Type != "hotp" {
+		return nil, fmt.Errorf("unsupported otp type: %s", otp.Type)
+	}
+	otp.Secret, err = DecodeOTPSecret(params.Get("secret"))
+	if err != nil {
+		return nil, err
+	}
+	if algorithm := params.Get("algorithm"); algorithm != "" {
+		otp.Algorithm = strings.ToUpper(algorithm)
+		if otp.hash() == nil {
+			return nil, fmt.Errorf("unsupported otp algorithm: %s", algorithm)
+		}
+	}
+	if digits := params.Get("digits"); digits != "" {
+		otp.Digits, err = strconv.Atoi(digits)
+		if err != nil || otp.Digits < 6 || otp.Digits > 10 {
+			return nil, fmt.Errorf("invalid otp digits: %s", digits)
+		}
+	}
+	if period := params.Get("period"); period != "" {
+		seconds, err := strconv.Atoi(period)
+		if err != nil || seconds <= 0 {
+			return nil, fmt.Errorf("invalid otp period: %s", period)
+		}
+		otp.Period = time.Duration(seconds) * time.Second
+	}
+	if otp.Type == "hotp" {
+		otp.Counter, err = strconv.ParseUint(params.Get("counter"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hotp counter: %s", params.Get("counter"))
+		}
+	}
+	return otp, nil
+}
+
+// DecodeOTPSecret decodes a base32 secret, ignoring case, spaces and
+// padding.
+func DecodeOTPSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.Join(strings.Fields(secret), ""))
+	secret = strings.TrimRight(secret, "=")
+	if secret == "" {
+		return nil, errors.New("otp secret is empty")
+	}
+	decoded, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid otp secret: %w", err)
+	}
+	return decoded, nil
+}
+
+// NewTOTPURI returns a TOTP URI with default parameters for a base32 secret.
+func NewTOTPURI(label, secret string) (string, error) {
+	_, err := DecodeOTPSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + label,
+		RawQuery: url.Values{"secret": {strings.ToUpper(strings.Join(strings.Fields(secret), ""))}}.Encode(),
+	}
+	return u.String(), nil
+}
+
+func (o *OTP) hash() func() hash.Hash {
+	switch o.Algorithm {
+	case "SHA1":
+		return sha1.New
+	case "SHA256":
+		return sha256.New
+	case "SHA512":
+		return sha512.New
+	default:
+		return nil
+	}
+}
+
+// HOTP returns the code for counter as described in RFC 4226.
+func (o *OTP) HOTP(counter uint64) string {
+	mac := hmac.New(o.hash(), o.Secret)
+	_ = binary.Write(mac, binary.BigEndian, counter)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff
+	mod := uint64(1)
+	for i := 0; i < o.Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", o.Digits, uint64(value)%mod)
+}
+
+// TOTP returns the code valid at t as described in RFC 6238, and how long it
+// stays valid.
+func (o *OTP) TOTP(t time.Time) (string, time.Duration) {
+	period := int64(o.Period / time.Second)
+	step := t.Unix() / period
+	next := time.Unix((step+1)*period, 0)
+	return o.HOTP(uint64(step)), next.Sub(t)
+}
+
+// URI encodes the generator, including its current counter, as otpauth URI.
+func (o *OTP) URI() string {
+	params := url.Values{}
+	for key, values := range o.params {
+		params[key] = values
+	}
+	if o.Type == "hotp" {
+		params.Set("counter", strconv.FormatUint(o.Counter, 10))
+	}
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     o.Type,
+		Path:     "/" + o.Label,
+		RawQuery: params.Encode(),
+	}
+	return u.String()
+}
+
+func otpLine(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "otpauth://") {
+		return line, true
+	}
+	key, value, ok := strings.Cut(line, ":")
+	if ok && strings.EqualFold(strings.TrimSpace(key), "otpauth") && strings.HasPrefix(strings.TrimSpace(value), "otpauth://") {
+		return strings.TrimSpace(value), true
+	}
+	return "", false
+}
+
+// OTP returns the one-time password generator stored in the entry, either
+// as a bare otpauth:// line or as "otpauth: otpauth://..." metadata.
+func (e Entry) OTP() (*OTP, error) {
+	for _, line := range append([]string{e.Secret}, e.Metadata...) {
+		if uri, ok := otpLine(line); ok {
+			return ParseOTP(uri)
+		}
+	}
+	return nil, ErrNoOTP
+}
+
+// SetOTP replaces the otpauth URI of the entry, or adds it as metadata.
+func (e *Entry) SetOTP(uri string) {
+	if current, ok := otpLine(e.Secret); ok {
+		e.Secret = replaceOTP(e.Secret, current, uri)
+		return
+	}
+	for i, line := range e.Metadata {
+		if current, ok := otpLine(line); ok {
+			e.Metadata[i] = replaceOTP(line, current, uri)
+			return
+		}
+	}
+	e.Metadata = append(e.Metadata, uri)
+}
+
+// replaceOTP replaces the current URI in line with uri, keeping the key of
+// metadata lines.
+func replaceOTP(line, current, uri string) string {
+	prefix, _, _ := strings.Cut(line, current)
+	return prefix + uri
+}
@@ -0,0 +1,68 @@
+package pass
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTPRFC6238(t *testing.T) {
+	for _, test := range []struct {
+		algorithm string
+		secret    string
+		time      int64
+		code      string
+	}{
+		{"SHA1", "12345678901234567890", 59, "94287082"},
+		{"SHA256", "12345678901234567890123456789012", 59, "46119246"},
+		{"SHA512", "1234567890123456789012345678901234567890123456789012345678901234", 59, "90693936"},
+		{"SHA1", "12345678901234567890", 1111111109, "07081804"},
+		{"SHA1", "12345678901234567890", 20000000000, "65353130"},
+	} {
+		secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte(test.secret))
+		otp, err := ParseOTP("otpauth://totp/test?digits=8&algorithm=" + test.algorithm + "&secret=" + secret)
+		require.NoError(t, err)
+		code, remaining := otp.TOTP(time.Unix(test.time, 0))
+		assert.Equal(t, test.code, code, "%s at %d", test.algorithm, test.time)
+		assert.Equal(t, time.Duration(30-test.time%30)*time.Second, remaining)
+	}
+}
+
+func TestHOTPRFC4226(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	otp, err := ParseOTP("otpauth://hotp/test?counter=0&secret=" + secret)
+	require.NoError(t, err)
+	for counter, code := range []string{"755224", "287082", "359152", "969429", "338314"} {
+		assert.Equal(t, code, otp.HOTP(uint64(counter)))
+	}
+	otp.Counter = 5
+	reparsed, err := ParseOTP(otp.URI())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), reparsed.Counter)
+}
+
+func TestEntryOTP(t *testing.T) {
+	entry := ParseEntry([]byte("hunter2\nuser: alice\n"))
+	_, err := entry.OTP()
+	assert.ErrorIs(t, err, ErrNoOTP)
+
+	uri, err := NewTOTPURI("alice", "jbsw y3dp ehpk 3pxp")
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP", uri)
+	entry.SetOTP(uri)
+	otp, err := entry.OTP()
+	require.NoError(t, err)
+	assert.Equal(t, "totp", otp.Type)
+	assert.Equal(t, []string{"user: alice"}, entry.PublicMetadata())
+
+	entry = ParseEntry([]byte("otpauth://totp/x?secret=JBSWY3DPEHPK3PXP\n"))
+	_, err = entry.OTP()
+	require.NoError(t, err)
+
+	entry = ParseEntry([]byte("hunter2\nOTPAuth: otpauth://totp/old?secret=JBSWY3DPEHPK3PXP\n"))
+	entry.SetOTP(uri)
+	assert.Equal(t, []string{"OTPAuth: " + uri}, entry.Metadata)
+}
@@ -0,0 +1,204 @@
+package pass
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"filippo.io/age"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"tasadar.net/tionis/shell-tools/crypt"
+)
+
+// recipientsDir returns the directory of subdir inside the store.
+func (s *Store) recipientsDir(subdir string) (string, error) {
+	if strings.Trim(subdir, "/") == "" {
+		return s.Dir, nil
+	}
+	return s.path(subdir)
+}
+
+// RecipientLines returns the recipients applying to entries directly in
+// subdir, as listed in the recipients file.
+func (s *Store) RecipientLines(subdir string) (file string, recipients []string, err error) {
+	dir, err := s.recipientsDir(subdir)
+	if err != nil {
+		return "", nil, err
+	}
+	rel, err := filepath.Rel(s.Dir, filepath.Join(dir, "entry"))
+	if err != nil {
+		return "", nil, err
+	}
+	file, err = s.RecipientsFileFor(filepath.ToSlash(rel))
+	if err != nil {
+		return "", nil, err
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", nil, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		recipients = append(recipients, line)
+	}
+	return file, recipients, scanner.Err()
+}
+
+// SetRecipients writes the recipients file of subdir. It does not
+// re-encrypt any entries.
+func (s *Store) SetRecipients(subdir string, recipients []string) (string, error) {
+	if len(recipients) == 0 {
+		return "", errors.New("at least one recipient is required")
+	}
+	_, err := crypt.ParseRecipients(strings.NewReader(strings.Join(recipients, "\n")))
+	if err != nil {
+		return "", fmt.Errorf("invalid recipient: %w", err)
+	}
+	dir, err := s.recipientsDir(subdir)
+	if err != nil {
+		return "", err
+	}
+	err = os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return "", err
+	}
+	file := filepath.Join(dir, RecipientsFile)
+	err = writeAtomic(file, []byte(strings.Join(recipients, "\n")+"\n"))
+	if err != nil {
+		return "", err
+	}
+	return file, s.commit("Set recipients of " + displayDir(subdir))
+}
+
+func displayDir(subdir string) string {
+	subdir = strings.Trim(subdir, "/")
+	if subdir == "" {
+		return "store"
+	}
+	return subdir
+}
+
+// AddRecipients adds recipients to those of subdir. If subdir has no
+// recipients file of its own yet, it is created from the inherited one, or
+// from scratch if there is none.
+func (s *Store) AddRecipients(subdir string, recipients []string) (string, error) {
+	_, current, err := s.RecipientLines(subdir)
+	if err != nil && s.hasRecipientsAbove(subdir) {
+		return "", err
+	}
+	for _, recipient := range recipients {
+		if !contains(current, recipient) {
+			current = append(current, recipient)
+		}
+	}
+	return s.SetRecipients(subdir, current)
+}
+
+// RemoveRecipients removes recipients from those of subdir. If subdir has no
+// recipients file of its own yet, it is created from the inherited one.
+func (s *Store) RemoveRecipients(subdir string, recipients []string) (string, error) {
+	_, current, err := s.RecipientLines(subdir)
+	if err != nil {
+		return "", err
+	}
+	var kept []string
+	for _, recipient := range current {
+		if !contains(recipients, recipient) {
+			kept = append(kept, recipient)
+		}
+	}
+	for _, recipient := range recipients {
+		if !contains(current, recipient) {
+			return "", fmt.Errorf("%s is not a recipient", recipient)
+		}
+	}
+	if len(kept) == 0 {
+		return "", errors.New("refusing to remove the last recipient")
+	}
+	return s.SetRecipients(subdir, kept)
+}
+
+func (s *Store) hasRecipientsAbove(subdir string) bool {
+	dir, err := s.recipientsDir(subdir)
+	if err != nil {
+		return false
+	}
+	for ; ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, RecipientsFile)); err == nil {
+			return true
+		}
+		if dir == filepath.Clean(s.Dir) || dir == filepath.Dir(dir) {
+			return false
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ReencryptResult is the outcome of re-encrypting a single entry.
+type ReencryptResult struct {
+	Name string
+	// RecipientsFile is the file listing the recipients the entry is
+	// (or would be) encrypted to.
+	RecipientsFile string
+	Err            error
+}
+
+// Reencrypt decrypts every entry below subdir and encrypts it again to the
+// recipients currently applying to it. Each entry is replaced atomically, a
+// failing entry does not stop the others. If only is not empty, just entries
+// using that recipients file are re-encrypted. With dryRun nothing is
+// written.
+func (s *Store) Reencrypt(subdir, only string, dryRun bool) ([]ReencryptResult, error) {
+	names, err := s.List(subdir)
+	if err != nil {
+		return nil, err
+	}
+	recipients := map[string][]age.Recipient{}
+	var results []ReencryptResult
+	for _, name := range names {
+		result := ReencryptResult{Name: name}
+		result.RecipientsFile, result.Err = s.RecipientsFileFor(name)
+		if result.Err == nil && only != "" && result.RecipientsFile != only {
+			continue
+		}
+		if result.Err == nil && !dryRun {
+			result.Err = s.reencrypt(name, result.RecipientsFile, recipients)
+		}
+		results = append(results, result)
+	}
+	if dryRun {
+		return results, nil
+	}
+	return results, s.commit("Re-encrypt entries of " + displayDir(subdir))
+}
+
+func (s *Store) reencrypt(name, file string, cache map[string][]age.Recipient) error {
+	recipients, ok := cache[file]
+	if !ok {
+		var err error
+		recipients, err = ReadRecipients(file)
+		if err != nil {
+			return err
+		}
+		cache[file] = recipients
+	}
+	data, err := s.Decrypt(name)
+	if err != nil {
+		return err
+	}
+	return s.encryptTo(name, data, recipients)
+}
@@ -0,0 +1,71 @@
+package pass
+
+import (
+	"filippo.io/age"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"tasadar.net/tionis/shell-tools/crypt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecipientsReencrypt(t *testing.T) {
+	store, owner := newTestStore(t)
+	newcomer, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	require.NoError(t, store.Insert("team/db", []byte("db-secret\n"), false))
+	require.NoError(t, store.Insert("private", []byte("mine\n"), false))
+
+	file, err := store.AddRecipients("team", []string{newcomer.Recipient().String()})
+	require.NoError(t, err)
+	_, recipients, err := store.RecipientLines("team")
+	require.NoError(t, err)
+	assert.Equal(t, []string{owner.Recipient().String(), newcomer.Recipient().String()}, recipients)
+
+	results, err := store.Reencrypt("", file, true)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "team/db", results[0].Name)
+
+	results, err = store.Reencrypt("", file, false)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.NoError(t, results[0].Err)
+
+	newcomerStore := &Store{Dir: store.Dir, Identities: []age.Identity{newcomer}}
+	data, err := newcomerStore.Decrypt("team/db")
+	require.NoError(t, err)
+	assert.Equal(t, "db-secret\n", string(data))
+	_, err = newcomerStore.Decrypt("private")
+	assert.Error(t, err)
+
+	_, err = store.RemoveRecipients("team", []string{owner.Recipient().String()})
+	require.NoError(t, err)
+	_, err = store.RemoveRecipients("team", []string{newcomer.Recipient().String()})
+	assert.Error(t, err, "removing the last recipient must fail")
+}
+
+func TestRecipientsSSH(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not found")
+	}
+	store, _ := newTestStore(t)
+	path := filepath.Join(t.TempDir(), "id_ed25519")
+	require.NoError(t, exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", path).Run())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	public, err := crypt.Recipients(data)
+	require.NoError(t, err)
+	_, err = store.AddRecipients("team", public)
+	require.NoError(t, err)
+	require.NoError(t, store.Insert("team/db", []byte("db-secret\n"), false))
+
+	identities, err := LoadIdentities(path, nil)
+	require.NoError(t, err)
+	decrypted, err := (&Store{Dir: store.Dir, Identities: identities}).Decrypt("team/db")
+	require.NoError(t, err)
+	assert.Equal(t, "db-secret\n", string(decrypted))
+}
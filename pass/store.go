@@ -0,0 +1,420 @@
+// Package pass implements a password store of age encrypted files.
+//
+// Every entry is a file ending in .age inside the store directory. Entries are
+// encrypted to the recipients listed in the nearest .age-recipients file,
+// searching from the directory of the entry up to the root of the store.
+package pass
+
+import (
+	"bytes"
+	"errors"
+	"filippo.io/age"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"tasadar.net/tionis/shell-tools/crypt"
+)
+
+const (
+	// Extension is the file extension of store entries.
+	Extension = ".age"
+	// RecipientsFile is the name of the files listing recipients.
+	RecipientsFile = ".age-recipients"
+)
+
+// ErrNotFound is returned if an entry does not exist.
+var ErrNotFound = errors.New("entry not found")
+
+// ErrExists is returned if an entry would be overwritten without force.
+var ErrExists = errors.New("entry already exists")
+
+// Store is a directory of age encrypted entries.
+type Store struct {
+	Dir        string
+	Identities []age.Identity
+}
+
+// path returns the file path of the entry name, without extension.
+func (s *Store) path(name string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(strings.Trim(name, "/")))
+	if clean == "." || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid entry name: %s", name)
+	}
+	return filepath.Join(s.Dir, clean), nil
+}
+
+// Exists reports whether the entry name exists.
+func (s *Store) Exists(name string) bool {
+	path, err := s.path(name)
+	if err != nil {
+		return false
+	}
+	stat, err := os.Stat(path + Extension)
+	return err == nil && stat.Mode().IsRegular()
+}
+
+// IsDir reports whether name is a directory of the store.
+func (s *Store) IsDir(name string) bool {
+	path := s.Dir
+	if strings.Trim(name, "/") != "" {
+		var err error
+		path, err = s.path(name)
+		if err != nil {
+			return false
+		}
+	}
+	stat, err := os.Stat(path)
+	return err == nil && stat.IsDir()
+}
+
+// RecipientsFileFor returns the recipients file applying to the entry name.
+func (s *Store) RecipientsFileFor(name string) (string, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return "", err
+	}
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		file := filepath.Join(dir, RecipientsFile)
+		if _, err := os.Stat(file); err == nil {
+			return file, nil
+		}
+		if dir == filepath.Clean(s.Dir) || dir == filepath.Dir(dir) {
+			return "", fmt.Errorf("no %s file found for %s", RecipientsFile, name)
+		}
+	}
+}
+
+// Recipients returns the recipients the entry name is encrypted to.
+func (s *Store) Recipients(name string) ([]age.Recipient, error) {
+	file, err := s.RecipientsFileFor(name)
+	if err != nil {
+		return nil, err
+	}
+	return ReadRecipients(file)
+}
+
+// ReadRecipients parses a recipients file of age X25519 recipients and SSH
+// public keys.
+func ReadRecipients(path string) ([]age.Recipient, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recipients: %w", err)
+	}
+	recipients, err := crypt.ParseRecipients(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse recipients file %s: %w", path, err)
+	}
+	return recipients, nil
+}
+
+// Decrypt returns the decrypted content of the entry name.
+func (s *Store) Decrypt(name string) ([]byte, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path + Extension)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	decrypted, err := age.Decrypt(file, s.Identities...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: %w", name, err)
+	}
+	return io.ReadAll(decrypted)
+}
+
+// Encrypt encrypts data to the recipients of the entry name and writes it
+// to the store, replacing an existing entry atomically.
+func (s *Store) Encrypt(name string, data []byte) error {
+	recipients, err := s.Recipients(name)
+	if err != nil {
+		return err
+	}
+	message := "Add " + name
+	if s.Exists(name) {
+		message = "Update " + name
+	}
+	err = s.encryptTo(name, data, recipients)
+	if err != nil {
+		return err
+	}
+	return s.commit(message)
+}
+
+func (s *Store) encryptTo(name string, data []byte, recipients []age.Recipient) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, recipients...)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt %s: %w", name, err)
+	}
+	_, err = w.Write(data)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt %s: %w", name, err)
+	}
+	err = w.Close()
+	if err != nil {
+		return fmt.Errorf("failed to encrypt %s: %w", name, err)
+	}
+	return writeAtomic(path+Extension, buf.Bytes())
+}
+
+// writeAtomic replaces the file at path with data, so readers see either
+// the old or the new content.
+func writeAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Insert adds a new entry. An existing entry is only replaced if force is set.
+func (s *Store) Insert(name string, data []byte, force bool) error {
+	if !force && s.Exists(name) {
+		return fmt.Errorf("%w: %s", ErrExists, name)
+	}
+	return s.Encrypt(name, data)
+}
+
+// Remove deletes the entry name. Directories are only removed if recursive
+// is set.
+func (s *Store) Remove(name string, recursive bool) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	switch {
+	case s.Exists(name):
+		err = os.Remove(path + Extension)
+	case !s.IsDir(name):
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	case !recursive:
+		return fmt.Errorf("%s is a directory", name)
+	default:
+		err = os.RemoveAll(path)
+	}
+	if err != nil {
+		return err
+	}
+	return s.commit("Remove " + name)
+}
+
+// Move renames the entry or directory from to to. Entries are re-encrypted
+// if the recipients of their new location differ. An existing entry is only
+// replaced if force is set.
+func (s *Store) Move(from, to string, force bool) error {
+	err := s.move(from, to, force)
+	if err != nil {
+		return err
+	}
+	return s.commit("Move " + from + " to " + to)
+}
+
+func (s *Store) move(from, to string, force bool) error {
+	if s.IsDir(from) {
+		names, err := s.List(from)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			err = s.move(name, strings.Trim(to, "/")+"/"+strings.TrimPrefix(name, strings.Trim(from, "/")+"/"), force)
+			if err != nil {
+				return err
+			}
+		}
+		path, _ := s.path(from)
+		return removeEmptyDirs(path)
+	}
+	if !s.Exists(from) {
+		return fmt.Errorf("%w: %s", ErrNotFound, from)
+	}
+	if s.IsDir(to) {
+		to = strings.Trim(to, "/") + "/" + filepath.Base(filepath.FromSlash(from))
+	}
+	if !force && s.Exists(to) {
+		return fmt.Errorf("%w: %s", ErrExists, to)
+	}
+	fromRecipients, err := s.RecipientsFileFor(from)
+	if err != nil {
+		return err
+	}
+	toRecipients, err := s.RecipientsFileFor(to)
+	if err != nil {
+		return err
+	}
+	fromPath, _ := s.path(from)
+	toPath, err := s.path(to)
+	if err != nil {
+		return err
+	}
+	if fromRecipients == toRecipients {
+		err = os.MkdirAll(filepath.Dir(toPath), 0o700)
+		if err != nil {
+			return err
+		}
+		return os.Rename(fromPath+Extension, toPath+Extension)
+	}
+	data, err := s.Decrypt(from)
+	if err != nil {
+		return err
+	}
+	recipients, err := ReadRecipients(toRecipients)
+	if err != nil {
+		return err
+	}
+	err = s.encryptTo(to, data, recipients)
+	if err != nil {
+		return err
+	}
+	return os.Remove(fromPath + Extension)
+}
+
+func removeEmptyDirs(root string) error {
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		// Directories still holding files, like recipient files, stay.
+		_ = os.Remove(dirs[i])
+	}
+	return nil
+}
+
+// List returns the names of all entries below subdir, sorted.
+func (s *Store) List(subdir string) ([]string, error) {
+	root := s.Dir
+	if strings.Trim(subdir, "/") != "" {
+		var err error
+		root, err = s.path(subdir)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var names []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && path != root && strings.HasPrefix(entry.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if entry.Type().IsRegular() && strings.HasSuffix(path, Extension) {
+			rel, err := filepath.Rel(s.Dir, strings.TrimSuffix(path, Extension))
+			if err != nil {
+				return err
+			}
+			names = append(names, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, subdir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Tree writes the entries below subdir as a tree.
+func (s *Store) Tree(w io.Writer, subdir string) error {
+	names, err := s.List(subdir)
+	if err != nil {
+		return err
+	}
+	title := strings.Trim(subdir, "/")
+	if title == "" {
+		title = "Password Store"
+	}
+	_, err = fmt.Fprintln(w, title)
+	if err != nil {
+		return err
+	}
+	prefix := ""
+	if trimmed := strings.Trim(subdir, "/"); trimmed != "" {
+		prefix = trimmed + "/"
+	}
+	root := &treeNode{}
+	for _, name := range names {
+		root.add(strings.Split(strings.TrimPrefix(name, prefix), "/"))
+	}
+	return root.write(w, "")
+}
+
+type treeNode struct {
+	names    []string
+	children map[string]*treeNode
+}
+
+func (n *treeNode) add(parts []string) {
+	if n.children == nil {
+		n.children = map[string]*treeNode{}
+	}
+	child, ok := n.children[parts[0]]
+	if !ok {
+		child = &treeNode{}
+		n.children[parts[0]] = child
+		n.names = append(n.names, parts[0])
+	}
+	if len(parts) > 1 {
+		child.add(parts[1:])
+	}
+}
+
+func (n *treeNode) write(w io.Writer, indent string) error {
+	sort.Strings(n.names)
+	for i, name := range n.names {
+		branch, next := "├── ", "│   "
+		if i == len(n.names)-1 {
+			branch, next = "└── ", "    "
+		}
+		_, err := fmt.Fprintln(w, indent+branch+name)
+		if err != nil {
+			return err
+		}
+		err = n.children[name].write(w, indent+next)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package pass
+
+import (
+	"bytes"
+	"filippo.io/age"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T) (*Store, *age.X25519Identity) {
+	t.Helper()
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, RecipientsFile), []byte(identity.Recipient().String()+"\n"), 0o644))
+	return &Store{Dir: dir, Identities: []age.Identity{identity}}, identity
+}
+
+func TestStoreInsertDecrypt(t *testing.T) {
+	store, _ := newTestStore(t)
+	require.NoError(t, store.Insert("web/example.com", []byte("hunter2\nuser: alice\n"), false))
+	assert.True(t, store.Exists("web/example.com"))
+	assert.True(t, store.IsDir("web"))
+
+	data, err := store.Decrypt("web/example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2\nuser: alice\n", string(data))
+
+	assert.ErrorIs(t, store.Insert("web/example.com", []byte("x"), false), ErrExists)
+	require.NoError(t, store.Insert("web/example.com", []byte("x"), true))
+
+	_, err = store.Decrypt("web/missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = store.Decrypt("../outside")
+	assert.Error(t, err)
+}
+
+func TestStoreMoveReencrypts(t *testing.T) {
+	store, _ := newTestStore(t)
+	other, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(store.Dir, "team"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(store.Dir, "team", RecipientsFile), []byte(other.Recipient().String()+"\n"), 0o644))
+
+	require.NoError(t, store.Insert("mail", []byte("secret\n"), false))
+	require.NoError(t, store.Move("mail", "team/mail", false))
+	assert.False(t, store.Exists("mail"))
+
+	store.Identities = []age.Identity{other}
+	data, err := store.Decrypt("team/mail")
+	require.NoError(t, err)
+	assert.Equal(t, "secret\n", string(data))
+}
+
+func TestStoreListTreeRemove(t *testing.T) {
+	store, _ := newTestStore(t)
+	for _, name := range []string{"b", "a/x", "a/y", "a/z/deep"} {
+		require.NoError(t, store.Insert(name, []byte(name), false))
+	}
+	names, err := store.List("")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a/x", "a/y", "a/z/deep", "b"}, names)
+
+	var tree bytes.Buffer
+	require.NoError(t, store.Tree(&tree, ""))
+	assert.Equal(t, "Password Store\n"+
+		"├── a\n"+
+		"│   ├── x\n"+
+		"│   ├── y\n"+
+		"│   └── z\n"+
+		"│       └── deep\n"+
+		"└── b\n", tree.String())
+
+	assert.Error(t, store.Remove("a", false))
+	require.NoError(t, store.Remove("a", true))
+	require.NoError(t, store.Remove("b", false))
+	names, err = store.List("")
+	require.NoError(t, err)
+	assert.Empty(t, names)
+}
+
+func TestLoadIdentitiesEncrypted(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	recipient, err := age.NewScryptRecipient("correct horse")
+	require.NoError(t, err)
+	recipient.SetWorkFactor(10)
+	var encrypted bytes.Buffer
+	w, err := age.Encrypt(&encrypted, recipient)
+	require.NoError(t, err)
+	_, err = w.Write([]byte("# test\n" + identity.String() + "\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	path := filepath.Join(t.TempDir(), "identities")
+	require.NoError(t, os.WriteFile(path, encrypted.Bytes(), 0o600))
+
+	identities, err := LoadIdentities(path, func() (string, error) {
+		return "correct horse", nil
+	})
+	require.NoError(t, err)
+	require.Len(t, identities, 2)
+	assert.Equal(t, identity.String(), identities[0].(*age.X25519Identity).String())
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"golang.org/x/term"
+	"os"
+	"strings"
+)
+
+// openTTY returns the controlling terminal, so prompts still work while
+// stdin and stdout are redirected.
+func openTTY() (*os.File, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err == nil {
+		return tty, nil
+	}
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		return os.Stdin, nil
+	}
+	return nil, errors.New("no terminal available")
+}
+
+// hasTerminal reports whether prompts can be shown.
+func hasTerminal() bool {
+	tty, err := openTTY()
+	if err != nil {
+		return false
+	}
+	if tty != os.Stdin {
+		tty.Close()
+	}
+	return true
+}
+
+// isTerminal reports whether file is connected to a terminal.
+func isTerminal(file *os.File) bool {
+	return term.IsTerminal(int(file.Fd()))
+}
+
+// readSecret prompts for a line on the terminal without echoing it.
+func readSecret(prompt string) (string, error) {
+	tty, err := openTTY()
+	if err != nil {
+		return "", err
+	}
+	if tty != os.Stdin {
+		defer tty.Close()
+	}
+	_, err = fmt.Fprint(tty, prompt)
+	if err != nil {
+		return "", err
+	}
+	secret, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(tty)
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+// readNewSecret prompts for a secret twice and makes sure both match.
+func readNewSecret(prompt string) (string, error) {
+	secret, err := readSecret(prompt + ": ")
+	if err != nil {
+		return "", err
+	}
+	again, err := readSecret("Retype " + strings.ToLower(prompt[:1]) + prompt[1:] + ": ")
+	if err != nil {
+		return "", err
+	}
+	if secret != again {
+		return "", errors.New("entries do not match")
+	}
+	return secret, nil
+}
+
+// readLine prompts for a line on the terminal.
+func readLine(prompt string) (string, error) {
+	tty, err := openTTY()
+	if err != nil {
+		return "", err
+	}
+	if tty != os.Stdin {
+		defer tty.Close()
+	}
+	_, err = fmt.Fprint(tty, prompt)
+	if err != nil {
+		return "", err
+	}
+	line, err := bufio.NewReader(tty).ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// confirm asks a yes/no question on the terminal, defaulting to no.
+func confirm(question string) (bool, error) {
+	answer, err := readLine(question + " [y/N] ")
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
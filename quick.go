@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ktr0731/go-fuzzyfinder"
+	"github.com/urfave/cli/v2"
+	"mvdan.cc/sh/v3/interp"
+	"os"
+	"os/exec"
+	"strings"
+	"tasadar.net/tionis/shell-tools/quick"
+	"time"
+)
+
+func quickCommand() *cli.Command {
+	return &cli.Command{
+		Name:    "quick",
+		Aliases: []string{"q"},
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:      "file",
+				Aliases:   []string{"f"},
+				Usage:     "config file to read, later files override earlier ones",
+				TakesFile: true,
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "format of a config read from stdin: json, yaml or toml",
+				Value: "json",
+			},
+			&cli.StringSliceFlag{
+				Name:    "tag",
+				Aliases: []string{"t"},
+				Usage:   "only offer commands with this tag",
+			},
+			&cli.BoolFlag{
+				Name:    "dry-run",
+				Aliases: []string{"n"},
+				Usage:   "print the commands that would be run instead of running them",
+			},
+			&cli.BoolFlag{
+				Name:    "trace",
+				Aliases: []string{"x"},
+				Usage:   "print every command to stderr before running it",
+			},
+			&cli.StringSliceFlag{
+				Name:  "allow",
+				Usage: "only allow running these executables, narrowing the allow list of the config",
+			},
+			&cli.StringSliceFlag{
+				Name:  "deny",
+				Usage: "deny running these executables, in addition to the deny list of the config",
+			},
+		},
+		Usage: "quick-commands, small ui to quickly execute some commands",
+		Description: "Configs map command names to commands with a description, the command itself and optionally\n" +
+			"a working directory (dir), environment overrides (env), a confirmation prompt (confirm),\n" +
+			"tags and the shell to use (interp for the builtin interpreter or sh for /bin/sh).\n" +
+			"Configs are read from --file, from stdin if it is not a terminal, or else from\n" +
+			"<user config dir>/shell-tools/quick.{yaml,yml,toml,json} merged with the closest\n" +
+			".quick.{yaml,yml,toml,json} up to the repository root.\n" +
+			"Commands may contain placeholders like {{branch}}, {{env:choice=dev,prod}} or {{file:glob=*.yaml}},\n" +
+			"which are asked for once a command is picked and inserted shell quoted.\n" +
+			"Entries of type source run their source command and offer every output line as item,\n" +
+			"inserted into their command as {{item}}. Picks are ranked by how often and how recently\n" +
+			"they were run, as recorded in $XDG_STATE_HOME/shell-tools/quick-history.json.\n" +
+			"With the interp shell, allow and deny restrict the executables a command may run and\n" +
+			"timeout (e.g. 30s) kills it once exceeded, exiting with 124. The reserved entry _defaults\n" +
+			"sets allow, deny and timeout for all commands of its config that do not set their own.",
+		Action: func(c *cli.Context) error {
+			config, err := loadQuickConfig(c)
+			if err != nil {
+				return err
+			}
+			var items []quick.Item
+			if c.Bool("dry-run") {
+				// Previews must not run the source commands of the config.
+				items = config.Entries(c.StringSlice("tag")...)
+			} else {
+				items, err = config.Items(context.Background(), os.Stderr, c.StringSlice("tag")...)
+				if err != nil {
+					logger.Warn("failed to generate items", "error", err)
+				}
+			}
+			if len(items) == 0 {
+				return errors.New("no commands configured")
+			}
+			history := loadQuickHistory()
+			names := make([]string, len(items))
+			byName := make(map[string]quick.Item, len(items))
+			for i, item := range items {
+				names[i] = item.Name
+				byName[item.Name] = item
+			}
+			history.Rank(names, time.Now())
+			selected, err := fuzzyfinder.Find(names, func(i int) string {
+				return names[i]
+			}, fuzzyfinder.WithPreviewWindow(func(i, w, h int) string {
+				if i < 0 {
+					return ""
+				}
+				return byName[names[i]].Command.Preview()
+			}))
+			if err != nil {
+				if errors.Is(err, fuzzyfinder.ErrAbort) {
+					return nil
+				}
+				return fmt.Errorf("failed to find command: %w", err)
+			}
+			item := byName[names[selected]]
+			if !c.Bool("dry-run") {
+				recordQuickHistory(history, item.Name)
+			}
+			return runQuickCommand(c, item.Name, item.Command, item.Values)
+		},
+		Subcommands: []*cli.Command{
+			{
+				Name:      "run",
+				Usage:     "run a command by name without the picker",
+				ArgsUsage: "name",
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{
+						Name:    "arg",
+						Aliases: []string{"a"},
+						Usage:   "value of a placeholder, given as name=value",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if c.Args().Len() == 0 {
+						return errors.New("no command name given")
+					}
+					argFlags, err := trailingArgFlags(c.Args().Tail())
+					if err != nil {
+						return err
+					}
+					config, err := loadQuickConfig(c)
+					if err != nil {
+						return err
+					}
+					name := c.Args().First()
+					command, ok := config[name]
+					if !ok {
+						return fmt.Errorf("unknown command: %s", name)
+					}
+					args := map[string]string{}
+					for _, arg := range append(c.StringSlice("arg"), argFlags...) {
+						key, value, ok := strings.Cut(arg, "=")
+						if !ok {
+							return fmt.Errorf("invalid argument %q, expected name=value", arg)
+						}
+						args[key] = value
+					}
+					item := name
+					if command.Type == quick.TypeSource {
+						if value, ok := args[quick.ItemPlaceholder]; ok {
+							item = name + ": " + value
+						}
+					}
+					if !c.Bool("dry-run") {
+						recordQuickHistory(loadQuickHistory(), item)
+					}
+					return runQuickCommand(c, name, command, args)
+				},
+			},
+		},
+	}
+}
+
+// loadQuickConfig reads the configs given by the flags, stdin or the
+// default config files.
+func loadQuickConfig(c *cli.Context) (quick.Config, error) {
+	files := c.StringSlice("file")
+	if len(files) == 0 && !isTerminal(os.Stdin) {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		config, err := quick.Parse(os.Stdin, c.String("format"), wd)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse config from stdin: %w", err)
+		}
+		return config, nil
+	}
+	if len(files) == 0 {
+		var err error
+		files, err = quick.DefaultFiles(".")
+		if err != nil {
+			return nil, err
+		}
+	}
+	var configs []quick.Config
+	for _, file := range files {
+		config, err := quick.Load(file)
+		if err != nil {
+			return nil, err
+		}
+		logger.Debug("loaded quick config", "path", file, "commands", len(config))
+		configs = append(configs, config)
+	}
+	return quick.Merge(configs...), nil
+}
+
+// trailingArgFlags returns the values of --arg flags given after the
+// command name, which the flag parser leaves as arguments.
+func trailingArgFlags(args []string) ([]string, error) {
+	var values []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--arg" || arg == "-a":
+			if i+1 == len(args) {
+				return nil, fmt.Errorf("missing value for %s", arg)
+			}
+			i++
+			values = append(values, args[i])
+		case strings.HasPrefix(arg, "--arg="):
+			values = append(values, strings.TrimPrefix(arg, "--arg="))
+		default:
+			return nil, fmt.Errorf("unexpected argument: %s", arg)
+		}
+	}
+	return values, nil
+}
+
+// loadQuickHistory returns the history of picked commands. Without a
+// readable history commands are still offered, just unranked.
+func loadQuickHistory() *quick.History {
+	path, err := quick.HistoryPath()
+	if err == nil {
+		var history *quick.History
+		history, err = quick.LoadHistory(path)
+		if err == nil {
+			return history
+		}
+	}
+	logger.Warn("failed to load quick history", "error", err)
+	return &quick.History{Entries: map[string]*quick.HistoryEntry{}}
+}
+
+// recordQuickHistory records that the item name was run.
+func recordQuickHistory(history *quick.History, name string) {
+	history.Record(name, time.Now())
+	err := history.Save()
+	if err != nil {
+		logger.Warn("failed to save quick history", "error", err)
+	}
+}
+
+// placeholderValues returns the values of the placeholders of command,
+// asking for those not given in args. In dry runs the source item is not
+// asked for, as that runs the source, and shown as placeholder instead.
+func placeholderValues(name string, command *quick.Command, args map[string]string, dryRun bool) (map[string]string, error) {
+	values := map[string]string{}
+	for _, placeholder := range command.Placeholders() {
+		if value, ok := args[placeholder.Name]; ok {
+			values[placeholder.Name] = value
+			continue
+		}
+		if dryRun && command.Type == quick.TypeSource && placeholder.Name == quick.ItemPlaceholder {
+			values[placeholder.Name] = "{{" + quick.ItemPlaceholder + "}}"
+			continue
+		}
+		if !hasTerminal() {
+			return nil, fmt.Errorf("missing value for %s, pass it with --arg %s=value", placeholder.Name, placeholder.Name)
+		}
+		value, err := promptPlaceholder(name, command, placeholder)
+		if err != nil {
+			return nil, err
+		}
+		values[placeholder.Name] = value
+	}
+	return values, nil
+}
+
+// promptPlaceholder asks for the value of placeholder, picking from its
+// choices or matching files if it has any.
+func promptPlaceholder(name string, command *quick.Command, placeholder quick.Placeholder) (string, error) {
+	candidates := placeholder.Choices
+	if command.Type == quick.TypeSource && placeholder.Name == quick.ItemPlaceholder {
+		var err error
+		candidates, err = command.SourceItems(context.Background(), name, os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		if len(candidates) == 0 {
+			return "", errors.New("source returned no items")
+		}
+	}
+	if placeholder.Glob != "" {
+		var err error
+		candidates, err = placeholder.Files(command.Dir)
+		if err != nil {
+			return "", err
+		}
+		if len(candidates) == 0 {
+			return "", fmt.Errorf("no files match %s for %s", placeholder.Glob, placeholder.Name)
+		}
+	}
+	if len(candidates) == 0 {
+		return readLine(placeholder.Name + ": ")
+	}
+	selected, err := fuzzyfinder.Find(candidates, func(i int) string {
+		return candidates[i]
+	}, fuzzyfinder.WithHeader(placeholder.Name))
+	if err != nil {
+		return "", err
+	}
+	return candidates[selected], nil
+}
+
+// runQuickCommand fills in the placeholders of command, asks for
+// confirmation if needed and runs it, exiting with its exit status.
+func runQuickCommand(c *cli.Context, name string, command *quick.Command, args map[string]string) error {
+	values, err := placeholderValues(name, command, args, c.Bool("dry-run"))
+	if errors.Is(err, fuzzyfinder.ErrAbort) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	command, err = command.Bind(values)
+	if err != nil {
+		return err
+	}
+	err = command.Restrict(c.StringSlice("allow"), c.StringSlice("deny"))
+	if err != nil {
+		return err
+	}
+	dryRun := c.Bool("dry-run")
+	if command.Confirm && !dryRun {
+		ok, err := confirm("Run " + name + "?")
+		if err != nil || !ok {
+			return err
+		}
+	}
+	err = command.Run(context.Background(), name, quick.RunOptions{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		DryRun: dryRun,
+		Trace:  c.Bool("trace"),
+	})
+	if errors.Is(err, quick.ErrTimeout) {
+		return cli.Exit(err.Error(), 124)
+	}
+	if status, ok := interp.IsExitStatus(err); ok {
+		return cli.Exit("", int(status))
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return cli.Exit("", exitErr.ExitCode())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to run command: %w", err)
+	}
+	return nil
+}
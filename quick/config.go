@@ -0,0 +1,294 @@
+// Package quick implements catalogs of named shell commands that are picked
+// and run interactively.
+package quick
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"tasadar.net/tionis/shell-tools/convert"
+	"time"
+)
+
+// Shells a command can be run with.
+const (
+	// ShellInterp runs commands with the builtin POSIX shell interpreter.
+	ShellInterp = "interp"
+	// ShellSh runs commands with /bin/sh.
+	ShellSh = "sh"
+)
+
+// Types of config entries.
+const (
+	// TypeCommand is a single command, the default.
+	TypeCommand = "command"
+	// TypeSource runs its source command and offers every line of its
+	// output as item, which is inserted into the command as {{item}}.
+	TypeSource = "source"
+)
+
+// Command is a named command of a config.
+type Command struct {
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description"`
+	Command     string `json:"command"`
+	// Source generates the items of a TypeSource entry.
+	Source string `json:"source,omitempty"`
+	// Dir is the working directory, relative to the config file.
+	Dir string `json:"dir,omitempty"`
+	// Env overrides variables of the environment.
+	Env map[string]string `json:"env,omitempty"`
+	// Confirm asks before the command is run.
+	Confirm bool     `json:"confirm,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	// Shell is ShellInterp, the default, or ShellSh.
+	Shell string `json:"shell,omitempty"`
+	// Allow lists the only executables the command may run, if set.
+	Allow []string `json:"allow,omitempty"`
+	// Deny lists executables the command must not run.
+	Deny []string `json:"deny,omitempty"`
+	// Timeout is the duration after which the command is killed.
+	Timeout string `json:"timeout,omitempty"`
+	timeout time.Duration
+}
+
+// DefaultsName is the name of the config entry whose allow, deny and timeout
+// settings apply to all commands of the config that do not set their own.
+const DefaultsName = "_defaults"
+
+// Config maps command names to commands.
+type Config map[string]*Command
+
+// Format returns the convert input format of a config file, guessed from its
+// extension.
+func Format(path string) string {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".toml":
+		return "toml"
+	default:
+		return "json"
+	}
+}
+
+// Parse reads a config in the given convert input format. Relative working
+// directories are resolved against dir.
+func Parse(r io.Reader, format, dir string) (Config, error) {
+	data, err := convert.Decode(format, nil, r)
+	if err != nil {
+		return nil, err
+	}
+	// The generic structure of the convert formats is mapped onto the
+	// config through its JSON representation.
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	var raw map[string]json.RawMessage
+	err = json.Unmarshal(encoded, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("config must map command names to commands: %w", err)
+	}
+	var defaults Command
+	if value, ok := raw[DefaultsName]; ok {
+		err = json.Unmarshal(value, &defaults)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", DefaultsName, err)
+		}
+		delete(raw, DefaultsName)
+	}
+	config := make(Config, len(raw))
+	for name, value := range raw {
+		command := &Command{Allow: slices.Clone(defaults.Allow), Deny: slices.Clone(defaults.Deny), Timeout: defaults.Timeout}
+		// A plain string is a command without description.
+		if json.Unmarshal(value, &command.Command) != nil {
+			err = json.Unmarshal(value, command)
+			if err != nil {
+				return nil, fmt.Errorf("invalid command %s: %w", name, err)
+			}
+		}
+		err = command.validate(dir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid command %s: %w", name, err)
+		}
+		config[name] = command
+	}
+	return config, nil
+}
+
+func (c *Command) validate(dir string) error {
+	if strings.TrimSpace(c.Command) == "" {
+		return errors.New("command is empty")
+	}
+	switch c.Type {
+	case "":
+		c.Type = TypeCommand
+	case TypeCommand:
+	case TypeSource:
+		if strings.TrimSpace(c.Source) == "" {
+			return errors.New("source is empty")
+		}
+	default:
+		return fmt.Errorf("unknown type %q, expected %s or %s", c.Type, TypeCommand, TypeSource)
+	}
+	switch c.Shell {
+	case "":
+		c.Shell = ShellInterp
+	case ShellInterp, ShellSh:
+	default:
+		return fmt.Errorf("unknown shell %q, expected %s or %s", c.Shell, ShellInterp, ShellSh)
+	}
+	if c.Timeout != "" {
+		timeout, err := time.ParseDuration(c.Timeout)
+		if err != nil || timeout <= 0 {
+			return fmt.Errorf("invalid timeout %q", c.Timeout)
+		}
+		c.timeout = timeout
+	}
+	if c.Dir == "~" || strings.HasPrefix(c.Dir, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("failed to get home dir: %w", err)
+		}
+		c.Dir = filepath.Join(home, c.Dir[1:])
+	}
+	if c.Dir != "" && !filepath.IsAbs(c.Dir) && dir != "" {
+		c.Dir = filepath.Join(dir, c.Dir)
+	}
+	return nil
+}
+
+// Load reads the config file at path.
+func Load(path string) (Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	defer file.Close()
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := Parse(file, Format(path), filepath.Dir(abs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// Merge combines configs, commands of later configs replace those of
+// earlier ones with the same name.
+func Merge(configs ...Config) Config {
+	merged := Config{}
+	for _, config := range configs {
+		for name, command := range config {
+			merged[name] = command
+		}
+	}
+	return merged
+}
+
+var extensions = []string{".yaml", ".yml", ".toml", ".json"}
+
+// findConfig returns the first existing file of base with one of the config
+// extensions.
+func findConfig(base string) (string, bool) {
+	for _, ext := range extensions {
+		if _, err := os.Stat(base + ext); err == nil {
+			return base + ext, true
+		}
+	}
+	return "", false
+}
+
+// DefaultFiles returns the existing config files that apply in dir: the
+// global config in the user config directory, followed by the .quick file
+// of the closest directory from dir up to the repository root.
+func DefaultFiles(dir string) ([]string, error) {
+	var files []string
+	configDir, err := os.UserConfigDir()
+	if err == nil {
+		if file, ok := findConfig(filepath.Join(configDir, "shell-tools", "quick")); ok {
+			files = append(files, file)
+		}
+	}
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if file, ok := findConfig(filepath.Join(dir, ".quick")); ok {
+			return append(files, file), nil
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil || dir == filepath.Dir(dir) {
+			return files, nil
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// Names returns the names of the commands having all of tags, sorted.
+func (c Config) Names(tags ...string) []string {
+	var names []string
+	for name, command := range c {
+		if command.hasTags(tags) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *Command) hasTags(tags []string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, own := range c.Tags {
+			found = found || own == tag
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Preview describes the command for the picker preview window.
+func (c *Command) Preview() string {
+	var b strings.Builder
+	if c.Description != "" {
+		b.WriteString(c.Description + "\n\n")
+	}
+	b.WriteString(c.Command + "\n")
+	if c.Type == TypeSource {
+		b.WriteString("\nitems: " + c.Source + "\n")
+	}
+	if len(c.Tags) > 0 {
+		b.WriteString("\ntags: " + strings.Join(c.Tags, ", ") + "\n")
+	}
+	if c.Dir != "" {
+		b.WriteString("dir: " + c.Dir + "\n")
+	}
+	if c.Shell == ShellSh {
+		b.WriteString("shell: /bin/sh\n")
+	}
+	if len(c.Allow) > 0 {
+		b.WriteString("allowed: " + strings.Join(c.Allow, ", ") + "\n")
+	}
+	if len(c.Deny) > 0 {
+		b.WriteString("denied: " + strings.Join(c.Deny, ", ") + "\n")
+	}
+	if c.Timeout != "" {
+		b.WriteString("timeout: " + c.Timeout + "\n")
+	}
+	if c.Confirm {
+		b.WriteString("asks for confirmation\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,77 @@
+package quick
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFormats(t *testing.T) {
+	for format, input := range map[string]string{
+		"json": `{"deploy": {"description": "ship it", "command": "make deploy", "dir": "app", "confirm": true, "tags": ["ops"]}, "ls": "ls -l"}`,
+		"yaml": "# ops commands\ndeploy:\n  description: ship it\n  command: make deploy\n  dir: app\n  confirm: true\n  tags: [ops]\nls: ls -l\n",
+		"toml": "ls = \"ls -l\"\n[deploy]\ndescription = \"ship it\"\ncommand = \"make deploy\"\ndir = \"app\"\nconfirm = true\ntags = [\"ops\"]\n",
+	} {
+		config, err := Parse(strings.NewReader(input), format, "/repo")
+		require.NoError(t, err, format)
+		assert.Equal(t, &Command{
+			Type:        TypeCommand,
+			Description: "ship it",
+			Command:     "make deploy",
+			Dir:         filepath.Join("/repo", "app"),
+			Confirm:     true,
+			Tags:        []string{"ops"},
+			Shell:       ShellInterp,
+		}, config["deploy"], format)
+		assert.Equal(t, "ls -l", config["ls"].Command, format)
+		assert.Equal(t, []string{"deploy", "ls"}, config.Names(), format)
+		assert.Equal(t, []string{"deploy"}, config.Names("ops"), format)
+	}
+
+	_, err := Parse(strings.NewReader(`{"x": {"command": "true", "shell": "fish"}}`), "json", "")
+	assert.ErrorContains(t, err, "unknown shell")
+	_, err = Parse(strings.NewReader(`{"x": {"description": "nothing"}}`), "json", "")
+	assert.ErrorContains(t, err, "command is empty")
+}
+
+func TestDefaultFilesMerge(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	configDir, err := os.UserConfigDir()
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(configDir, "shell-tools"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(configDir, "shell-tools", "quick.yaml"), []byte("a: echo global\nb: echo global\n"), 0o644))
+
+	repo := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(repo, ".git"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "sub", "dir"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".quick.toml"), []byte("b = \"echo repo\"\n"), 0o644))
+
+	files, err := DefaultFiles(filepath.Join(repo, "sub", "dir"))
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	var configs []Config
+	for _, file := range files {
+		config, err := Load(file)
+		require.NoError(t, err)
+		configs = append(configs, config)
+	}
+	merged := Merge(configs...)
+	assert.Equal(t, "echo global", merged["a"].Command)
+	assert.Equal(t, "echo repo", merged["b"].Command)
+}
+
+func TestRunDirEnv(t *testing.T) {
+	dir := t.TempDir()
+	for _, shell := range []string{ShellInterp, ShellSh} {
+		command := &Command{Command: `echo "$GREETING" "$(pwd)"`, Dir: dir, Env: map[string]string{"GREETING": "hi"}, Shell: shell}
+		var out bytes.Buffer
+		require.NoError(t, command.Run(context.Background(), "greet", RunOptions{Stdout: &out, Stderr: &out}), shell)
+		assert.Equal(t, "hi "+dir+"\n", out.String(), shell)
+	}
+}
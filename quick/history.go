@@ -0,0 +1,141 @@
+package quick
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// maxHistory is the number of entries kept in the history, the lowest ranked
+// ones are dropped first.
+const maxHistory = 500
+
+// HistoryEntry records how often and when an item was last run.
+type HistoryEntry struct {
+	Count int       `json:"count"`
+	Last  time.Time `json:"last"`
+}
+
+// History ranks items by frecency, a combination of how frequently and how
+// recently they were run.
+type History struct {
+	path    string
+	Entries map[string]*HistoryEntry
+}
+
+// HistoryPath returns the path of the history file in $XDG_STATE_HOME,
+// falling back to ~/.local/state.
+func HistoryPath() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home dir: %w", err)
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "shell-tools", "quick-history.json"), nil
+}
+
+// LoadHistory reads the history at path. A missing file is an empty history.
+func LoadHistory(path string) (*History, error) {
+	history := &History{path: path, Entries: map[string]*HistoryEntry{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	err = json.Unmarshal(data, &history.Entries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse history %s: %w", path, err)
+	}
+	return history, nil
+}
+
+// Record notes that the item name was run at now.
+func (h *History) Record(name string, now time.Time) {
+	entry, ok := h.Entries[name]
+	if !ok {
+		entry = &HistoryEntry{}
+		h.Entries[name] = entry
+	}
+	entry.Count++
+	entry.Last = now
+}
+
+// Score returns the frecency of the item name at now, its run count
+// weighted by how long ago it was last run.
+func (h *History) Score(name string, now time.Time) float64 {
+	entry, ok := h.Entries[name]
+	if !ok {
+		return 0
+	}
+	age := now.Sub(entry.Last)
+	weight := 0.25
+	switch {
+	case age < time.Hour:
+		weight = 4
+	case age < 24*time.Hour:
+		weight = 2
+	case age < 7*24*time.Hour:
+		weight = 1
+	case age < 30*24*time.Hour:
+		weight = 0.5
+	}
+	return float64(entry.Count) * weight
+}
+
+// Rank sorts names by descending frecency, keeping the order of names with
+// the same score.
+func (h *History) Rank(names []string, now time.Time) {
+	sort.SliceStable(names, func(i, j int) bool {
+		return h.Score(names[i], now) > h.Score(names[j], now)
+	})
+}
+
+// Save writes the history, dropping the lowest ranked entries beyond the
+// size limit. A history that was not loaded from a file is not saved.
+func (h *History) Save() error {
+	if h.path == "" {
+		return nil
+	}
+	if len(h.Entries) > maxHistory {
+		names := make([]string, 0, len(h.Entries))
+		for name := range h.Entries {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		h.Rank(names, time.Now())
+		for _, name := range names[maxHistory:] {
+			delete(h.Entries, name)
+		}
+	}
+	data, err := json.Marshal(h.Entries)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(h.path), 0o700)
+	if err != nil {
+		return fmt.Errorf("failed to create state dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(h.path), ".quick-history.*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), h.path)
+}
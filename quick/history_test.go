@@ -0,0 +1,57 @@
+package quick
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistoryRank(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "history.json")
+	history, err := LoadHistory(path)
+	require.NoError(t, err)
+	now := time.Now()
+	// Run often, but long ago.
+	for i := 0; i < 5; i++ {
+		history.Record("old", now.Add(-60*24*time.Hour))
+	}
+	history.Record("recent", now.Add(-time.Minute))
+	history.Record("recent", now.Add(-time.Minute))
+	require.NoError(t, history.Save())
+
+	history, err = LoadHistory(path)
+	require.NoError(t, err)
+	names := []string{"a", "old", "b", "recent"}
+	history.Rank(names, now)
+	assert.Equal(t, []string{"recent", "old", "a", "b"}, names)
+}
+
+func TestSourceItems(t *testing.T) {
+	config := Config{
+		"checkout": {Type: TypeSource, Source: "echo main; echo; echo 'feature x'", Command: "git checkout {{item}}", Shell: ShellInterp},
+		"status":   {Type: TypeCommand, Command: "git status", Shell: ShellInterp},
+		"broken":   {Type: TypeSource, Source: "exit 1", Command: "true", Shell: ShellInterp},
+	}
+	items, err := config.Items(context.Background(), io.Discard)
+	assert.ErrorContains(t, err, "source of broken failed")
+	require.Len(t, items, 3)
+	assert.Equal(t, "checkout: main", items[0].Name)
+	assert.Equal(t, "checkout: feature x", items[1].Name)
+	assert.Equal(t, "status", items[2].Name)
+
+	bound, err := items[1].Command.Bind(items[1].Values)
+	require.NoError(t, err)
+	assert.Equal(t, "git checkout 'feature x'", bound.Command)
+
+	dir := t.TempDir()
+	config = Config{"touch": {Type: TypeSource, Source: "touch ran", Command: "true", Dir: dir, Shell: ShellInterp}}
+	items = config.Entries()
+	require.Len(t, items, 1)
+	assert.Equal(t, "touch", items[0].Name)
+	assert.NoFileExists(t, filepath.Join(dir, "ran"))
+}
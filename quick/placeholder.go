@@ -0,0 +1,116 @@
+package quick
+
+import (
+	"fmt"
+	"mvdan.cc/sh/v3/syntax"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// placeholderPattern matches {{name}}, {{name:choice=a,b}} and
+// {{name:glob=*.yaml}}.
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_-]*)\s*(?::\s*(choice|glob)\s*=([^}]*))?\}\}`)
+
+// Placeholder is a value a command asks for before it is run.
+type Placeholder struct {
+	Name string
+	// Choices are the allowed values, if any.
+	Choices []string
+	// Glob selects files to pick the value from, if set.
+	Glob string
+}
+
+// Placeholders returns the placeholders of the command in order of their
+// first occurrence. A placeholder that occurs several times takes the same
+// value everywhere, its options are taken from where they are given.
+func (c *Command) Placeholders() []Placeholder {
+	var placeholders []Placeholder
+	index := map[string]int{}
+	for _, match := range placeholderPattern.FindAllStringSubmatch(c.Command, -1) {
+		placeholder := Placeholder{Name: match[1]}
+		switch match[2] {
+		case "choice":
+			for _, choice := range strings.Split(match[3], ",") {
+				if choice = strings.TrimSpace(choice); choice != "" {
+					placeholder.Choices = append(placeholder.Choices, choice)
+				}
+			}
+		case "glob":
+			placeholder.Glob = strings.TrimSpace(match[3])
+		}
+		i, ok := index[placeholder.Name]
+		if !ok {
+			index[placeholder.Name] = len(placeholders)
+			placeholders = append(placeholders, placeholder)
+		} else if match[2] != "" {
+			placeholders[i] = placeholder
+		}
+	}
+	return placeholders
+}
+
+// Expand returns the command with its placeholders replaced by the shell
+// quoted values. Values of placeholders with choices must be one of them.
+func (c *Command) Expand(values map[string]string) (string, error) {
+	for _, placeholder := range c.Placeholders() {
+		value, ok := values[placeholder.Name]
+		if !ok {
+			return "", fmt.Errorf("missing value for %s", placeholder.Name)
+		}
+		if len(placeholder.Choices) > 0 && !contains(placeholder.Choices, value) {
+			return "", fmt.Errorf("invalid value %q for %s, expected one of %s", value, placeholder.Name, strings.Join(placeholder.Choices, ", "))
+		}
+	}
+	var err error
+	expanded := placeholderPattern.ReplaceAllStringFunc(c.Command, func(match string) string {
+		name := placeholderPattern.FindStringSubmatch(match)[1]
+		quoted, quoteErr := syntax.Quote(values[name], syntax.LangPOSIX)
+		if quoteErr != nil && err == nil {
+			err = fmt.Errorf("failed to quote value for %s: %w", name, quoteErr)
+		}
+		return quoted
+	})
+	return expanded, err
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Bind returns a copy of the command with its placeholders expanded.
+func (c *Command) Bind(values map[string]string) (*Command, error) {
+	expanded, err := c.Expand(values)
+	if err != nil {
+		return nil, err
+	}
+	bound := *c
+	bound.Command = expanded
+	return &bound, nil
+}
+
+// Files returns the files matching the glob of the placeholder, relative to
+// dir, or the current directory if dir is empty.
+func (p Placeholder) Files(dir string) ([]string, error) {
+	if dir == "" {
+		dir = "."
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, p.Glob))
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %w", p.Glob, err)
+	}
+	files := make([]string, 0, len(matches))
+	for _, match := range matches {
+		rel, err := filepath.Rel(dir, match)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, rel)
+	}
+	return files, nil
+}
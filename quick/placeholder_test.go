@@ -0,0 +1,39 @@
+package quick
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlaceholders(t *testing.T) {
+	command := &Command{Command: "deploy {{branch}} to {{ env:choice=dev, staging,prod }} with {{file:glob=*.yaml}} from {{branch}}"}
+	assert.Equal(t, []Placeholder{
+		{Name: "branch"},
+		{Name: "env", Choices: []string{"dev", "staging", "prod"}},
+		{Name: "file", Glob: "*.yaml"},
+	}, command.Placeholders())
+
+	bound, err := command.Bind(map[string]string{"branch": "feature/it's", "env": "prod", "file": "a b.yaml"})
+	require.NoError(t, err)
+	assert.Equal(t, `deploy "feature/it's" to prod with 'a b.yaml' from "feature/it's"`, bound.Command)
+	assert.Contains(t, command.Command, "{{branch}}")
+
+	_, err = command.Expand(map[string]string{"branch": "main", "env": "qa", "file": "x"})
+	assert.ErrorContains(t, err, "invalid value")
+	_, err = command.Expand(map[string]string{"env": "dev"})
+	assert.ErrorContains(t, err, "missing value for branch")
+}
+
+func TestPlaceholderFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.yaml", "b.yaml", "c.json"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o644))
+	}
+	files, err := Placeholder{Name: "file", Glob: "*.yaml"}.Files(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.yaml", "b.yaml"}, files)
+}
@@ -0,0 +1,255 @@
+package quick
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/interp"
+	"mvdan.cc/sh/v3/syntax"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrTimeout is returned if a command ran longer than its timeout.
+var ErrTimeout = errors.New("command timed out")
+
+// RunOptions control how a command is run.
+type RunOptions struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	// DryRun prints every command to Stdout instead of running it.
+	DryRun bool
+	// Trace prints every command to Stderr before it is run, like set -x.
+	Trace bool
+}
+
+// Environ returns the environment of the command: the current environment
+// with the overrides of the command.
+func (c *Command) Environ() []string {
+	env := os.Environ()
+	keys := make([]string, 0, len(c.Env))
+	for key := range c.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		env = append(env, key+"="+c.Env[key])
+	}
+	return env
+}
+
+// Run runs the command named name. Its timeout, allowlist and denylist are
+// only enforced as long as ctx is not done.
+func (c *Command) Run(ctx context.Context, name string, opts RunOptions) error {
+	if opts.Stdout == nil {
+		opts.Stdout = io.Discard
+	}
+	if opts.Stderr == nil {
+		opts.Stderr = io.Discard
+	}
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	var err error
+	if c.Shell == ShellSh {
+		err = c.runSh(ctx, opts)
+	} else {
+		err = c.runInterp(ctx, name, opts)
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %s", ErrTimeout, c.timeout)
+	}
+	return err
+}
+
+// runSh runs the command with /bin/sh, which can not be restricted to
+// allowed executables.
+func (c *Command) runSh(ctx context.Context, opts RunOptions) error {
+	if len(c.Allow) > 0 || len(c.Deny) > 0 {
+		return errors.New("allow and deny lists need the interp shell")
+	}
+	if opts.DryRun {
+		_, err := fmt.Fprintln(opts.Stdout, c.Command)
+		return err
+	}
+	args := []string{"-c", c.Command}
+	if opts.Trace {
+		args = append([]string{"-x"}, args...)
+	}
+	cmd := exec.CommandContext(ctx, "/bin/sh", args...)
+	cmd.Dir = c.Dir
+	cmd.Env = c.Environ()
+	cmd.Stdin = opts.Stdin
+	cmd.Stdout = opts.Stdout
+	cmd.Stderr = opts.Stderr
+	// Children of the killed shell may keep its output open.
+	cmd.WaitDelay = time.Second
+	return cmd.Run()
+}
+
+func (c *Command) runInterp(ctx context.Context, name string, opts RunOptions) error {
+	file, err := syntax.NewParser().Parse(strings.NewReader(c.Command), name)
+	if err != nil {
+		return fmt.Errorf("failed to parse command: %w", err)
+	}
+	// Redirections are opened before their command is called, so they are
+	// collected and printed along with it. Pipelines call handlers
+	// concurrently.
+	var mu sync.Mutex
+	var redirects []string
+	runner, err := interp.New(
+		interp.Env(expand.ListEnviron(c.Environ()...)),
+		interp.Dir(c.Dir),
+		interp.StdIO(opts.Stdin, opts.Stdout, opts.Stderr),
+		interp.OpenHandler(func(ctx context.Context, path string, flag int, perm os.FileMode) (io.ReadWriteCloser, error) {
+			mu.Lock()
+			redirects = append(redirects, redirectOperator(flag)+" "+quoteArgs([]string{path}))
+			mu.Unlock()
+			if opts.DryRun {
+				// Dry runs must not create or truncate files.
+				return nopFile{}, nil
+			}
+			return interp.DefaultOpenHandler()(ctx, path, flag, perm)
+		}),
+		interp.CallHandler(func(ctx context.Context, args []string) ([]string, error) {
+			mu.Lock()
+			line := strings.Join(append([]string{quoteArgs(args)}, redirects...), " ")
+			redirects = nil
+			mu.Unlock()
+			if opts.Trace {
+				fmt.Fprintln(opts.Stderr, "+", line)
+			}
+			if opts.DryRun {
+				fmt.Fprintln(opts.Stdout, line)
+				return []string{"true"}, nil
+			}
+			return args, nil
+		}),
+		interp.ExecHandlers(func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+			return func(ctx context.Context, args []string) error {
+				err := c.checkExecutable(ctx, args[0])
+				if err != nil {
+					return err
+				}
+				return next(ctx, args)
+			}
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set up interpreter: %w", err)
+	}
+	return runner.Run(ctx, file)
+}
+
+// redirectOperator returns the shell operator of a redirection opening a
+// file with flag.
+func redirectOperator(flag int) string {
+	switch {
+	case flag&os.O_APPEND != 0:
+		return ">>"
+	case flag&os.O_RDWR != 0:
+		return "<>"
+	case flag&os.O_WRONLY != 0:
+		return ">"
+	default:
+		return "<"
+	}
+}
+
+// nopFile stands in for the files of redirections in dry runs. It reads as
+// empty and discards what is written.
+type nopFile struct{}
+
+func (nopFile) Read([]byte) (int, error) {
+	return 0, io.EOF
+}
+
+func (nopFile) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (nopFile) Close() error {
+	return nil
+}
+
+// Restrict narrows the executables the command may run: allow further limits
+// its allowlist, or becomes it if the command has none, and deny is added to
+// its denylist. It returns an error if no executable is allowed by both.
+func (c *Command) Restrict(allow, deny []string) error {
+	if len(allow) > 0 {
+		if len(c.Allow) == 0 {
+			c.Allow = slices.Clone(allow)
+		} else {
+			var both []string
+			for _, entry := range c.Allow {
+				if slices.Contains(allow, entry) {
+					both = append(both, entry)
+				}
+			}
+			if len(both) == 0 {
+				return fmt.Errorf("none of %s is in the allowlist %s", strings.Join(allow, ", "), strings.Join(c.Allow, ", "))
+			}
+			c.Allow = both
+		}
+	}
+	c.Deny = append(slices.Clone(c.Deny), deny...)
+	return nil
+}
+
+// checkExecutable returns an error if the executable is denied or not
+// allowed. Names in the lists match executables called by name, paths match
+// the executable the call resolves to. Denied names also match executables
+// called by path.
+func (c *Command) checkExecutable(ctx context.Context, executable string) error {
+	handlerCtx := interp.HandlerCtx(ctx)
+	resolved, err := interp.LookPathDir(handlerCtx.Dir, handlerCtx.Env, executable)
+	if err != nil {
+		// Left to the exec handler to report.
+		resolved = ""
+	}
+	byName := !strings.ContainsRune(executable, '/')
+	matches := func(list []string, anyName bool) bool {
+		for _, entry := range list {
+			if strings.ContainsRune(entry, '/') {
+				if resolved != "" && filepath.Clean(entry) == resolved {
+					return true
+				}
+			} else if byName && entry == executable || anyName && entry == filepath.Base(executable) {
+				return true
+			}
+		}
+		return false
+	}
+	if matches(c.Deny, true) {
+		return fmt.Errorf("executable %s is denied", executable)
+	}
+	if len(c.Allow) > 0 && !matches(c.Allow, false) {
+		return fmt.Errorf("executable %s is not allowed", executable)
+	}
+	return nil
+}
+
+// quoteArgs joins args to a shell command line.
+func quoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		q, err := syntax.Quote(arg, syntax.LangBash)
+		if err != nil {
+			q = strconv.Quote(arg)
+		}
+		quoted[i] = q
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,101 @@
+package quick
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunDryRunTrace(t *testing.T) {
+	dir := t.TempDir()
+	command := &Command{Command: "touch created && echo \"done $USER\"", Dir: dir, Env: map[string]string{"USER": "me"}, Shell: ShellInterp}
+	var out bytes.Buffer
+	require.NoError(t, command.Run(context.Background(), "x", RunOptions{Stdout: &out, DryRun: true}))
+	assert.Equal(t, "touch created\necho 'done me'\n", out.String())
+	assert.NoFileExists(t, filepath.Join(dir, "created"))
+
+	var stdout, stderr bytes.Buffer
+	require.NoError(t, command.Run(context.Background(), "x", RunOptions{Stdout: &stdout, Stderr: &stderr, Trace: true}))
+	assert.Equal(t, "done me\n", stdout.String())
+	assert.Equal(t, "+ touch created\n+ echo 'done me'\n", stderr.String())
+	assert.FileExists(t, filepath.Join(dir, "created"))
+}
+
+func TestRunDryRunRedirects(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "important.conf")
+	require.NoError(t, os.WriteFile(conf, []byte("old\n"), 0o644))
+	command := &Command{Command: "echo new > important.conf; cat < important.conf 2>>log", Dir: dir, Shell: ShellInterp}
+	var out bytes.Buffer
+	require.NoError(t, command.Run(context.Background(), "x", RunOptions{Stdout: &out, DryRun: true}))
+	assert.Equal(t, "echo new > important.conf\ncat < important.conf >> log\n", out.String())
+	data, err := os.ReadFile(conf)
+	require.NoError(t, err)
+	assert.Equal(t, "old\n", string(data))
+	assert.NoFileExists(t, filepath.Join(dir, "log"))
+}
+
+func TestRunAllowDeny(t *testing.T) {
+	command := &Command{Command: "echo hi && rm -rf nothing", Deny: []string{"rm"}, Shell: ShellInterp}
+	var out bytes.Buffer
+	err := command.Run(context.Background(), "x", RunOptions{Stdout: &out})
+	assert.ErrorContains(t, err, "executable rm is denied")
+	assert.Equal(t, "hi\n", out.String(), "builtins are not restricted")
+
+	command = &Command{Command: "ls / >/dev/null; /bin/cat /dev/null", Allow: []string{"ls"}, Shell: ShellInterp}
+	err = command.Run(context.Background(), "x", RunOptions{})
+	assert.ErrorContains(t, err, "executable /bin/cat is not allowed")
+
+	dir := t.TempDir()
+	tool := filepath.Join(dir, "ls")
+	require.NoError(t, os.WriteFile(tool, []byte("#!/bin/sh\n"), 0o755))
+	command = &Command{Command: "./ls", Dir: dir, Allow: []string{"ls"}, Shell: ShellInterp}
+	err = command.Run(context.Background(), "x", RunOptions{})
+	assert.ErrorContains(t, err, "executable ./ls is not allowed", "names do not match paths")
+	command = &Command{Command: "./ls && PATH=" + dir + " ls", Dir: dir, Allow: []string{tool}, Shell: ShellInterp}
+	assert.NoError(t, command.Run(context.Background(), "x", RunOptions{}), "paths match resolved executables")
+
+	command = &Command{Command: "rm -f nothing", Allow: []string{"git"}, Deny: []string{"curl"}, Shell: ShellInterp}
+	assert.ErrorContains(t, command.Restrict([]string{"rm"}, nil), "none of rm")
+	assert.Equal(t, []string{"git"}, command.Allow, "allowlists only narrow")
+	require.NoError(t, command.Restrict([]string{"git", "rm"}, []string{"wget"}))
+	assert.Equal(t, []string{"git"}, command.Allow)
+	assert.Equal(t, []string{"curl", "wget"}, command.Deny)
+	err = command.Run(context.Background(), "x", RunOptions{})
+	assert.ErrorContains(t, err, "executable rm is not allowed")
+
+	command = &Command{Command: "true", Allow: []string{"ls"}, Shell: ShellSh}
+	err = command.Run(context.Background(), "x", RunOptions{})
+	assert.ErrorContains(t, err, "need the interp shell")
+}
+
+func TestRunTimeout(t *testing.T) {
+	config, err := Parse(strings.NewReader(`{"_defaults": {"timeout": "100ms", "deny": ["rm"]}, "slow": "sleep 3", "own": {"command": "true", "timeout": "1m"}}`), "json", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"own", "slow"}, config.Names())
+	assert.Equal(t, []string{"rm"}, config["own"].Deny)
+	assert.Equal(t, "1m", config["own"].Timeout)
+	config["own"].Deny = append(config["own"].Deny, "curl")
+	config["slow"].Deny = append(config["slow"].Deny, "wget")
+	assert.Equal(t, []string{"rm", "curl"}, config["own"].Deny, "defaults are not shared")
+
+	for _, shell := range []string{ShellInterp, ShellSh} {
+		slow := *config["slow"]
+		slow.Deny = nil
+		slow.Shell = shell
+		start := time.Now()
+		err = slow.Run(context.Background(), "slow", RunOptions{})
+		assert.ErrorIs(t, err, ErrTimeout, shell)
+		assert.Less(t, time.Since(start), 2*time.Second, shell)
+	}
+
+	_, err = Parse(strings.NewReader(`{"x": {"command": "true", "timeout": "soon"}}`), "json", "")
+	assert.ErrorContains(t, err, "invalid timeout")
+}
@@ -0,0 +1,80 @@
+package quick
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ItemPlaceholder is the placeholder source items are inserted as.
+const ItemPlaceholder = "item"
+
+// Item is an entry of the picker: a command, or one item of a source.
+type Item struct {
+	// Name identifies the item in the picker and the history.
+	Name    string
+	Command *Command
+	// Values are the placeholder values the item provides.
+	Values map[string]string
+}
+
+// SourceItems runs the source of the command and returns its non-empty
+// output lines.
+func (c *Command) SourceItems(ctx context.Context, name string, stderr io.Writer) ([]string, error) {
+	source := *c
+	source.Command = c.Source
+	var out bytes.Buffer
+	err := source.Run(ctx, name, RunOptions{Stdout: &out, Stderr: stderr})
+	if err != nil {
+		return nil, fmt.Errorf("source of %s failed: %w", name, err)
+	}
+	var items []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			items = append(items, line)
+		}
+	}
+	return items, nil
+}
+
+// Items returns the picker items of the commands having all of tags.
+// Sources are run to generate their items; the items of the other entries
+// are returned along with the errors of failing sources.
+func (c Config) Items(ctx context.Context, stderr io.Writer, tags ...string) ([]Item, error) {
+	var items []Item
+	var errs []error
+	for _, name := range c.Names(tags...) {
+		command := c[name]
+		if command.Type != TypeSource {
+			items = append(items, Item{Name: name, Command: command})
+			continue
+		}
+		lines, err := command.SourceItems(ctx, name, stderr)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, line := range lines {
+			items = append(items, Item{
+				Name:    name + ": " + line,
+				Command: command,
+				Values:  map[string]string{ItemPlaceholder: line},
+			})
+		}
+	}
+	return items, errors.Join(errs...)
+}
+
+// Entries returns a picker item for every command having all of tags without
+// running any source, so source entries are not split into their items.
+func (c Config) Entries(tags ...string) []Item {
+	names := c.Names(tags...)
+	items := make([]Item, len(names))
+	for i, name := range names {
+		items[i] = Item{Name: name, Command: c[name]}
+	}
+	return items
+}
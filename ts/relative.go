@@ -0,0 +1,150 @@
+package ts
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"tasadar.net/tionis/shell-tools/convert/regex2json"
+	"time"
+)
+
+// layout is a time layout with the range of lengths of the timestamps it
+// formats, so only substrings of those lengths have to be tried.
+type layout struct {
+	layout   string
+	min, max int
+	hasYear  bool
+	hasDate  bool
+}
+
+var layouts = newLayouts(regex2json.TimeLayouts) //nolint: gochecknoglobals
+
+func newLayouts(timeLayouts map[string]string) []layout {
+	samples := []time.Time{
+		time.Date(2023, 5, 7, 4, 5, 6, 0, time.UTC),
+		time.Date(2023, 9, 27, 14, 25, 36, 123456789, time.FixedZone("CEST", 2*60*60)),
+	}
+	seen := map[string]bool{}
+	var result []layout
+	for _, value := range timeLayouts {
+		if seen[value] {
+			continue
+		}
+		seen[value] = true
+		l := layout{
+			layout:  value,
+			min:     len(value),
+			hasYear: strings.Contains(value, "2006") || strings.Contains(value, "06"),
+			hasDate: strings.Contains(value, "Jan") || strings.Contains(value, "01") || strings.Contains(value, "02") || strings.Contains(value, "_2"),
+		}
+		for _, sample := range samples {
+			n := len(sample.Format(value))
+			if n < l.min {
+				l.min = n
+			}
+			if n > l.max {
+				l.max = n
+			}
+		}
+		result = append(result, l)
+	}
+	// Keep the order of tried layouts independent of map iteration.
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].layout < result[j].layout
+	})
+	return result
+}
+
+// parse parses s as timestamp of the layout, filling in the year or date
+// missing from the layout from now.
+func (l layout) parse(s string, now time.Time) (time.Time, bool) {
+	t, err := time.ParseInLocation(l.layout, s, now.Location())
+	if err != nil {
+		return time.Time{}, false
+	}
+	if !l.hasDate {
+		year, month, day := now.Date()
+		t = time.Date(year, month, day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	} else if !l.hasYear {
+		t = t.AddDate(now.Year()-t.Year(), 0, 0)
+		if t.After(now.Add(24 * time.Hour)) {
+			t = t.AddDate(-1, 0, 0)
+		}
+	}
+	return t, true
+}
+
+// Relative replaces the timestamps in line, recognized by the layouts of
+// [regex2json.TimeLayouts], with how long before now they were, or with the
+// timestamp formatted by the strftime format if it is not empty.
+func Relative(line string, now time.Time, format string) string {
+	var b strings.Builder
+	for i := 0; i < len(line); i++ {
+		if i > 0 && isWordByte(line[i-1]) || !isWordByte(line[i]) {
+			b.WriteByte(line[i])
+			continue
+		}
+		var found time.Time
+		end := 0
+		for _, l := range layouts {
+			for n := l.max; n >= l.min && i+n > end; n-- {
+				if i+n > len(line) || i+n < len(line) && isWordByte(line[i+n]) {
+					continue
+				}
+				if t, ok := l.parse(line[i:i+n], now); ok {
+					found, end = t, i+n
+					break
+				}
+			}
+		}
+		if end == 0 {
+			b.WriteByte(line[i])
+			continue
+		}
+		if format == "" {
+			b.WriteString(Ago(now.Sub(found)))
+		} else {
+			b.WriteString(Strftime(format, found))
+		}
+		i = end - 1
+	}
+	return b.String()
+}
+
+func isWordByte(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// Ago formats d like "5m ago" or "1h30m ago", using its two most
+// significant units. Negative durations are formatted like "in 5m".
+func Ago(d time.Duration) string {
+	future := d < 0
+	if future {
+		d = -d
+	}
+	units := []struct {
+		suffix string
+		size   time.Duration
+	}{{"d", 24 * time.Hour}, {"h", time.Hour}, {"m", time.Minute}, {"s", time.Second}}
+	var parts []string
+	for _, unit := range units {
+		count := d / unit.size
+		d -= count * unit.size
+		if count > 0 {
+			parts = append(parts, strconv.FormatInt(int64(count), 10)+unit.suffix)
+		} else if len(parts) > 0 {
+			break
+		}
+		if len(parts) == 2 {
+			break
+		}
+	}
+	text := strings.Join(parts, "")
+	if text == "" {
+		text = "0s"
+	}
+	if future {
+		return "in " + text
+	}
+	return text + " ago"
+}
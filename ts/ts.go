@@ -0,0 +1,140 @@
+// Package ts implements the ts command, which timestamps lines of input.
+package ts
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Default formats of absolute timestamps and of elapsed times.
+const (
+	DefaultFormat        = "%b %d %H:%M:%S"
+	DefaultElapsedFormat = "%H:%M:%S"
+)
+
+// Modes of timestamping.
+const (
+	// ModeAbsolute prefixes lines with the time they were read.
+	ModeAbsolute = iota
+	// ModeIncremental prefixes lines with the time since the previous line.
+	ModeIncremental
+	// ModeSinceStart prefixes lines with the time since the start.
+	ModeSinceStart
+	// ModeRelative replaces timestamps in lines with how long ago they were.
+	ModeRelative
+)
+
+// Options configure [Run].
+type Options struct {
+	// Format is the strftime format of timestamps. Elapsed times are
+	// formatted as time since the epoch in UTC.
+	Format string
+	Mode   int
+	// Monotonic measures time with the monotonic clock, so changes of the
+	// wall clock do not affect timestamps after the start.
+	Monotonic bool
+	// JSON writes every line as {"ts": ..., "line": ...}. Without a format,
+	// ts is an RFC 3339 timestamp or the elapsed seconds.
+	JSON bool
+	// Now returns the current time, it defaults to time.Now.
+	Now func() time.Time
+}
+
+type jsonLine struct {
+	TS   any    `json:"ts"`
+	Line string `json:"line"`
+}
+
+// Run copies lines from r to w, timestamping them as configured by opts.
+// Output is flushed whenever no more input is buffered, so lines are
+// written as soon as they are read.
+func Run(r io.Reader, w io.Writer, opts Options) error {
+	if opts.JSON && opts.Mode == ModeRelative {
+		return errors.New("json output can not be combined with relative timestamps")
+	}
+	now := opts.Now
+	if now == nil {
+		now = time.Now
+	}
+	clock := func() time.Time {
+		if opts.Monotonic {
+			return now()
+		}
+		return now().Round(0)
+	}
+	reader := bufio.NewReader(r)
+	writer := bufio.NewWriter(w)
+	encoder := json.NewEncoder(writer)
+	encoder.SetEscapeHTML(false)
+	start := clock()
+	last := start
+	for {
+		line, readErr := reader.ReadString('\n')
+		if line != "" {
+			t := clock()
+			elapsed := t.Sub(last)
+			if opts.Mode == ModeSinceStart {
+				elapsed = t.Sub(start)
+			}
+			last = t
+			// Keep absolute timestamps in step with the monotonic clock.
+			t = start.Round(0).Add(t.Sub(start))
+			var err error
+			switch {
+			case opts.Mode == ModeRelative:
+				_, err = writer.WriteString(Relative(line, t, opts.Format))
+			case opts.JSON:
+				err = encoder.Encode(jsonLine{TS: jsonStamp(opts, t, elapsed), Line: strings.TrimSuffix(line, "\n")})
+			default:
+				_, err = writer.WriteString(stamp(opts, t, elapsed) + " " + line)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to write line: %w", err)
+			}
+		}
+		if readErr != nil || reader.Buffered() == 0 {
+			err := writer.Flush()
+			if err != nil {
+				return fmt.Errorf("failed to write line: %w", err)
+			}
+		}
+		if errors.Is(readErr, io.EOF) {
+			return nil
+		}
+		if readErr != nil {
+			return fmt.Errorf("failed to read line: %w", readErr)
+		}
+	}
+}
+
+// stamp formats the timestamp of a line read at t, elapsed after the
+// previous line or the start.
+func stamp(opts Options, t time.Time, elapsed time.Duration) string {
+	format := opts.Format
+	if opts.Mode == ModeAbsolute {
+		if format == "" {
+			format = DefaultFormat
+		}
+		return Strftime(format, t)
+	}
+	if format == "" {
+		format = DefaultElapsedFormat
+	}
+	return Strftime(format, time.Unix(0, 0).UTC().Add(elapsed))
+}
+
+func jsonStamp(opts Options, t time.Time, elapsed time.Duration) any {
+	switch {
+	case opts.Format != "":
+		return stamp(opts, t, elapsed)
+	case opts.Mode == ModeAbsolute:
+		return t.Format(time.RFC3339Nano)
+	default:
+		return elapsed.Seconds()
+	}
+}
@@ -0,0 +1,70 @@
+package ts
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock returns start and then advances by the given steps on every call.
+func fakeClock(start time.Time, steps ...time.Duration) func() time.Time {
+	now := start
+	return func() time.Time {
+		current := now
+		if len(steps) > 0 {
+			now = now.Add(steps[0])
+			steps = steps[1:]
+		}
+		return current
+	}
+}
+
+func TestRun(t *testing.T) {
+	start := time.Date(2023, 10, 18, 12, 0, 0, 250000000, time.UTC)
+	input := "first\nsecond\nthird"
+	for _, test := range []struct {
+		name     string
+		opts     Options
+		expected string
+	}{
+		{"absolute", Options{}, "Oct 18 12:00:01 first\nOct 18 12:00:03 second\nOct 18 12:01:03 third"},
+		{"format", Options{Format: "%F %.T"}, "2023-10-18 12:00:01.250000 first\n2023-10-18 12:00:03.250000 second\n2023-10-18 12:01:03.250000 third"},
+		{"incremental", Options{Mode: ModeIncremental}, "00:00:01 first\n00:00:02 second\n00:01:00 third"},
+		{"since start", Options{Mode: ModeSinceStart, Format: "%M:%.S"}, "00:01.000000 first\n00:03.000000 second\n01:03.000000 third"},
+		{"json", Options{JSON: true}, `{"ts":"2023-10-18T12:00:01.25Z","line":"first"}` + "\n" + `{"ts":"2023-10-18T12:00:03.25Z","line":"second"}` + "\n" + `{"ts":"2023-10-18T12:01:03.25Z","line":"third"}` + "\n"},
+		{"json elapsed", Options{JSON: true, Mode: ModeIncremental}, `{"ts":1,"line":"first"}` + "\n" + `{"ts":2,"line":"second"}` + "\n" + `{"ts":60,"line":"third"}` + "\n"},
+	} {
+		test.opts.Now = fakeClock(start, time.Second, 2*time.Second, time.Minute)
+		var out bytes.Buffer
+		require.NoError(t, Run(strings.NewReader(input), &out, test.opts), test.name)
+		assert.Equal(t, test.expected, out.String(), test.name)
+	}
+}
+
+func TestRelative(t *testing.T) {
+	now := time.Date(2023, 10, 18, 12, 0, 0, 0, time.UTC)
+	for input, expected := range map[string]string{
+		"2023-10-18T11:55:00Z GET /":                       "5m ago GET /",
+		"[2023-10-18 10:29:50.123] started":                "[1h30m ago] started",
+		"Oct 17 11:00:00 host sshd[1]: accepted":           "1d1h ago host sshd[1]: accepted",
+		"at 11:59:30 and 2023/10/18 12:00:10, id 20231018": "at 30s ago and in 10s, id 20231018",
+		"no timestamps here":                               "no timestamps here",
+	} {
+		assert.Equal(t, expected, Relative(input, now, ""), input)
+	}
+	assert.Equal(t, "[10:29:50] started", Relative("[2023-10-18 10:29:50.123] started", now, "%T"))
+
+	var out bytes.Buffer
+	opts := Options{Mode: ModeRelative, Now: fakeClock(now)}
+	require.NoError(t, Run(strings.NewReader("2023-10-18T11:55:00Z GET /\n"), &out, opts))
+	assert.Equal(t, "5m ago GET /\n", out.String())
+}
+
+func TestStrftime(t *testing.T) {
+	date := time.Date(2023, 3, 5, 7, 8, 9, 0, time.FixedZone("CET", 60*60))
+	assert.Equal(t, "Sun Mar  5 07:08:09 2023 +0100 CET 064 7 AM %q 100%", Strftime("%c %z %Z %j %u %p %q 100%%", date))
+}